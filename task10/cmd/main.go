@@ -1,8 +1,11 @@
 package main
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pozedorum/WB_project_2/task10/internal/sortpkg"
 	"github.com/pozedorum/WB_project_2/task10/pkg/options"
@@ -11,28 +14,58 @@ import (
 func main() {
 	fs, args := options.ParseOptions()
 
-	// Определяем источник ввода
-	if len(args) == 1 {
-		//fmt.Println("here")
-		// Сортировка файла с выводом в stdout
-		err := sortpkg.ExternalSortToStdout(args[0], *fs)
-		if err != nil {
-			log.Fatal(err)
-		}
-	} else if len(args) == 0 {
-		// Проверяем, есть ли данные в stdin
-		stat, _ := os.Stdin.Stat()
-		if (stat.Mode() & os.ModeCharDevice) == 0 {
-			// Сортировка stdin с выводом в stdout
-			err := sortpkg.ProcessStdio(*fs)
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			sortpkg.ProcessInteractiveInput(*fs)
-			//log.Fatal("No input file or stdin data provided")
+	// При прерывании удаляем временные файлы, как GNU sort
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		sortpkg.RemoveTempFiles()
+		os.Exit(130)
+	}()
+
+	// С -o результат атомарно заменяет файл, который может быть и входом
+	if *fs.OFlag != "" {
+		if len(args) == 0 {
+			args = []string{"-"}
 		}
+		err := sortpkg.ExternalSort(args, *fs.OFlag, *fs)
+		exitOnError(err, *fs.CQuietFlag)
+		return
+	}
+
+	// Определяем источник ввода
+	if len(args) > 0 {
+		// Сортировка (или слияние с -m) файлов с выводом в stdout, "-" - stdin
+		err := sortpkg.ExternalSortToStdout(args, *fs)
+		exitOnError(err, *fs.CQuietFlag)
+		return
+	}
+
+	// Проверяем, есть ли данные в stdin
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) == 0 {
+		// Сортировка stdin с выводом в stdout
+		err := sortpkg.ProcessStdio(*fs)
+		exitOnError(err, *fs.CQuietFlag)
 	} else {
-		log.Fatal("too much files ")
+		err := sortpkg.ProcessInteractiveInput(*fs)
+		exitOnError(err, *fs.CQuietFlag)
+	}
+}
+
+// exitOnError завершает программу с кодом как у GNU sort:
+// 1 - вход не отсортирован при -c/-C, 2 - остальные ошибки
+func exitOnError(err error, quiet bool) {
+	if err == nil {
+		return
+	}
+	var disorder *sortpkg.DisorderError
+	if errors.As(err, &disorder) {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "sort: %v\n", err)
+		}
+		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "sort: %v\n", err)
+	os.Exit(2)
 }
@@ -2,4 +2,7 @@ module github.com/pozedorum/WB_project_2/task10
 
 go 1.24.1
 
-require github.com/spf13/pflag v1.0.7
+require (
+	github.com/spf13/pflag v1.0.7
+	golang.org/x/text v0.28.0
+)
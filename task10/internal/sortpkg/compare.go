@@ -0,0 +1,613 @@
+package sortpkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pozedorum/WB_project_2/task10/pkg/options"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// sortMode определяет, как интерпретируется ключ
+type sortMode int
+
+const (
+	modeLexical sortMode = iota
+	modeNumeric
+	modeHuman
+	modeMonth
+	modeCollate // строки по правилам локали
+	modeGeneral
+	modeVersion
+	modeRandom // хеш ключа с секретом: равные ключи рядом, группы в случайном порядке
+)
+
+// sortKey - ключ, разобранный один раз для строки
+type sortKey struct {
+	text string            // для лексического сравнения
+	coll []byte            // ключ сортировки по правилам локали
+	num  decimal           // для -n и -h
+	rank int               // номер месяца для -M, порядок суффикса для -h, вид значения для -g
+	flt  *big.Float        // для -g, nil для NaN и ошибок разбора
+	neg  bool              // знак NaN для -g
+	hash [sha256.Size]byte // для -R
+}
+
+// keySpec описывает, какая часть строки является ключом и как её сравнивать
+type keySpec struct {
+	options.KeyDef
+	mode    sortMode
+	reverse bool
+}
+
+// Comparator сравнивает строки так же, как GNU sort в локали C
+type Comparator struct {
+	keys    []keySpec
+	sep     string // разделитель полей, пустая строка - пробельные символы
+	csv     bool   // строки - записи CSV/TSV, поля разбираются splitRecord
+	reverse bool   // глобальный -r, влияет и на последнее сравнение целых строк
+	unique  bool
+	stable  bool   // -s, без последнего сравнения целых строк
+	seed    []byte // ключ хеширования для -R
+	// collators раздаёт коллаторы горутинам: collate.Collator нельзя использовать конкурентно
+	collators *sync.Pool
+}
+
+// NewComparator строит компаратор по настройкам. Ключи без собственных модификаторов
+// наследуют глобальные, без ключей ключом служит вся строка
+func NewComparator(cfg Config) *Comparator {
+	global := cfg.Global
+	global.StartField, global.StartChar = 1, 1
+	global.EndField, global.EndChar = 0, 0
+	defs := cfg.Keys
+	if len(defs) == 0 {
+		defs = []options.KeyDef{global}
+	}
+
+	keys := make([]keySpec, len(defs))
+	for i, def := range defs {
+		if !def.HasOptions() {
+			def.SkipStartBlanks, def.SkipEndBlanks = global.SkipStartBlanks, global.SkipEndBlanks
+			def.Numeric, def.Human, def.Month = global.Numeric, global.Human, global.Month
+			def.General, def.Version, def.Random = global.General, global.Version, global.Random
+			def.Reverse = global.Reverse
+			def.Fold, def.Dictionary = global.Fold, global.Dictionary
+		}
+		keys[i] = keySpec{KeyDef: def, mode: modeOf(def), reverse: def.Reverse}
+	}
+
+	c := &Comparator{keys: keys, sep: cfg.Separator, csv: cfg.CSV, reverse: global.Reverse, unique: cfg.Unique, stable: cfg.Stable}
+	if c.seed = cfg.RandomSeed; len(c.seed) == 0 && slices.ContainsFunc(keys, func(k keySpec) bool { return k.mode == modeRandom }) {
+		// Один компаратор на всю сортировку, так что чанки и слияние используют один ключ
+		c.seed = make([]byte, options.RandomSeedSize)
+		rand.Read(c.seed)
+	}
+	if cfg.Locale != "" {
+		tag := language.Make(cfg.Locale)
+		c.collators = &sync.Pool{New: func() any { return collate.New(tag) }}
+		for i := range c.keys {
+			if c.keys[i].mode == modeLexical {
+				c.keys[i].mode = modeCollate
+			}
+		}
+	}
+	return c
+}
+
+func modeOf(def options.KeyDef) sortMode {
+	switch {
+	case def.Random:
+		return modeRandom
+	case def.General:
+		return modeGeneral
+	case def.Version:
+		return modeVersion
+	case def.Human:
+		return modeHuman
+	case def.Numeric:
+		return modeNumeric
+	case def.Month:
+		return modeMonth
+	default:
+		return modeLexical
+	}
+}
+
+// keyedLine - строка вместе с разобранными ключами
+type keyedLine struct {
+	line string
+	keys []sortKey
+}
+
+// makeKeyed разбирает ключи строки
+func (c *Comparator) makeKeyed(line string) keyedLine {
+	keys := make([]sortKey, len(c.keys))
+	if c.csv {
+		fields := splitRecord(line, c.sep[0])
+		for i, spec := range c.keys {
+			keys[i] = c.parseKey(spec, c.extractField(spec, fields))
+		}
+		return keyedLine{line, keys}
+	}
+	for i, spec := range c.keys {
+		keys[i] = c.parseKey(spec, c.extractKey(spec, line))
+	}
+	return keyedLine{line, keys}
+}
+
+// resolveColumns проставляет номера полей ключам, заданным именем столбца
+func (c *Comparator) resolveColumns(header []string) error {
+	for i := range c.keys {
+		key := &c.keys[i]
+		if key.Name == "" {
+			continue
+		}
+		col := slices.Index(header, key.Name)
+		if col < 0 {
+			return fmt.Errorf("unknown column %q", key.Name)
+		}
+		key.StartField, key.EndField = col+1, col+1
+	}
+	return nil
+}
+
+// compareKeys сравнивает только ключи
+func (c *Comparator) compareKeys(a, b keyedLine) int {
+	for i, spec := range c.keys {
+		res := compareKey(spec.mode, a.keys[i], b.keys[i])
+		if spec.reverse {
+			res = -res
+		}
+		if res != 0 {
+			return res
+		}
+	}
+	return 0
+}
+
+// compare сравнивает ключи, а при их равенстве - строки целиком (кроме режимов -u и -s)
+func (c *Comparator) compare(a, b keyedLine) int {
+	if res := c.compareKeys(a, b); res != 0 || c.unique || c.stable {
+		return res
+	}
+	res := strings.Compare(a.line, b.line)
+	if c.reverse {
+		res = -res
+	}
+	return res
+}
+
+// Compare сравнивает две строки: <0, если a идёт раньше b
+func (c *Comparator) Compare(a, b string) int {
+	return c.compare(c.makeKeyed(a), c.makeKeyed(b))
+}
+
+// isBlank как field_sep в GNU sort: перевод строки тоже пробельный, он встречается внутри записей с -z
+func isBlank(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n'
+}
+
+// extractKey возвращает часть строки, заданную ключом, по правилам GNU sort.
+// Без -t поля разделяются переходом от непробельного символа к пробельному,
+// и ведущие пробелы относятся к полю
+func (c *Comparator) extractKey(spec keySpec, line string) string {
+	beg := c.skipFields(line, spec.StartField-1, true)
+	if spec.SkipStartBlanks {
+		beg = skipBlanks(line, beg)
+	}
+	beg = min(len(line), beg+spec.StartChar-1)
+
+	end := len(line)
+	if spec.EndField > 0 {
+		if spec.EndChar == 0 {
+			// Ключ включает поле конца целиком
+			end = c.skipFields(line, spec.EndField, false)
+		} else {
+			end = c.skipFields(line, spec.EndField-1, true)
+			if spec.SkipEndBlanks {
+				end = skipBlanks(line, end)
+			}
+			end = min(len(line), end+spec.EndChar)
+		}
+	}
+	if end < beg {
+		return ""
+	}
+	return line[beg:end]
+}
+
+// extractField возвращает ключ записи CSV/TSV: поля от начала до конца ключа
+// через разделитель, позиции символов отсчитываются внутри первого и последнего поля
+func (c *Comparator) extractField(spec keySpec, fields []string) string {
+	first, last := spec.StartField-1, len(fields)-1
+	if spec.EndField > 0 {
+		last = min(last, spec.EndField-1)
+	}
+	if first > last {
+		return ""
+	}
+	parts := slices.Clone(fields[first : last+1])
+	if spec.EndChar > 0 && last == spec.EndField-1 {
+		end := 0
+		if spec.SkipEndBlanks {
+			end = skipBlanks(parts[len(parts)-1], 0)
+		}
+		parts[len(parts)-1] = parts[len(parts)-1][:min(len(parts[len(parts)-1]), end+spec.EndChar)]
+	}
+	beg := 0
+	if spec.SkipStartBlanks {
+		beg = skipBlanks(parts[0], 0)
+	}
+	parts[0] = parts[0][min(len(parts[0]), beg+spec.StartChar-1):]
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts, c.sep)
+}
+
+// skipFields пропускает n полей от начала строки. С -t разделитель после последнего поля
+// пропускается только если stepOver, как в limfield из GNU sort
+func (c *Comparator) skipFields(line string, n int, stepOver bool) int {
+	pos := 0
+	for ; pos < len(line) && n > 0; n-- {
+		if c.sep != "" {
+			if i := strings.IndexByte(line[pos:], c.sep[0]); i >= 0 {
+				pos += i
+			} else {
+				pos = len(line)
+			}
+			if pos < len(line) && (n > 1 || stepOver) {
+				pos++
+			}
+			continue
+		}
+		pos = skipBlanks(line, pos)
+		for pos < len(line) && !isBlank(line[pos]) {
+			pos++
+		}
+	}
+	return pos
+}
+
+func skipBlanks(line string, pos int) int {
+	for pos < len(line) && isBlank(line[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func (c *Comparator) parseKey(spec keySpec, text string) sortKey {
+	switch spec.mode {
+	case modeNumeric:
+		num, _ := parseDecimal(trimBlanks(text))
+		return sortKey{num: num}
+	case modeHuman:
+		num, rest := parseDecimal(trimBlanks(text))
+		return sortKey{num: num, rank: unitOrder(num, rest)}
+	case modeMonth:
+		return sortKey{rank: parseMonth(text)}
+	case modeGeneral:
+		flt, neg, rank := parseGeneral(text)
+		return sortKey{flt: flt, neg: neg, rank: rank}
+	case modeCollate:
+		return sortKey{coll: c.collationKey(transformText(spec, text, true))}
+	case modeRandom:
+		// Хешируется то, что сравнивалось бы без -R, чтобы равные ключи попали в одну группу
+		key := sortKey{text: transformText(spec, text, c.collators != nil)}
+		if c.collators != nil {
+			key.coll = c.collationKey(key.text)
+			key.text = ""
+		}
+		h := sha256.New()
+		h.Write(c.seed)
+		h.Write(key.coll)
+		h.Write([]byte(key.text))
+		h.Sum(key.hash[:0])
+		return key
+	default:
+		return sortKey{text: transformText(spec, text, false)}
+	}
+}
+
+func (c *Comparator) collationKey(text string) []byte {
+	col := c.collators.Get().(*collate.Collator)
+	defer c.collators.Put(col)
+	var buf collate.Buffer
+	return col.KeyFromString(&buf, text)
+}
+
+// transformText применяет к текстовому ключу -d и -f. Без локали ключ обрабатывается
+// по байтам, как в локали C: не-ASCII байты и невалидный UTF-8 не меняются и не теряются.
+// С локалью используются правила Unicode.
+func transformText(spec keySpec, text string, unicodeRules bool) string {
+	if !spec.Dictionary && !spec.Fold {
+		return text
+	}
+	if !unicodeRules {
+		buf := make([]byte, 0, len(text))
+		for i := 0; i < len(text); i++ {
+			ch := text[i]
+			if spec.Dictionary && !(ch == ' ' || ch == '\t' || isAlnum(ch)) {
+				continue
+			}
+			if spec.Fold && 'a' <= ch && ch <= 'z' {
+				ch -= 'a' - 'A'
+			}
+			buf = append(buf, ch)
+		}
+		return string(buf)
+	}
+
+	if spec.Dictionary {
+		text = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, text)
+	}
+	if spec.Fold {
+		text = strings.ToUpper(text)
+	}
+	return text
+}
+
+func compareKey(mode sortMode, a, b sortKey) int {
+	switch mode {
+	case modeNumeric:
+		return a.num.compare(b.num)
+	case modeHuman:
+		if a.rank != b.rank {
+			return compareInts(a.rank, b.rank)
+		}
+		return a.num.compare(b.num)
+	case modeMonth:
+		return compareInts(a.rank, b.rank)
+	case modeCollate:
+		return bytes.Compare(a.coll, b.coll)
+	case modeGeneral:
+		switch {
+		case a.rank != b.rank:
+			return compareInts(a.rank, b.rank)
+		case a.rank == generalNaN:
+			// Как memcmp в GNU sort: NaN со знаком минус идут после положительных
+			return compareBools(a.neg, b.neg)
+		case a.rank == generalNumber:
+			return a.flt.Cmp(b.flt)
+		}
+		return 0
+	case modeVersion:
+		return compareVersions(a.text, b.text)
+	case modeRandom:
+		// При совпадении хешей разные ключи всё равно не смешиваются
+		if res := bytes.Compare(a.hash[:], b.hash[:]); res != 0 {
+			return res
+		}
+		if res := bytes.Compare(a.coll, b.coll); res != 0 {
+			return res
+		}
+		return strings.Compare(a.text, b.text)
+	default:
+		return strings.Compare(a.text, b.text)
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func trimBlanks(s string) string {
+	return strings.TrimLeft(s, " \t")
+}
+
+// decimal - число произвольной длины в виде строк цифр, без потери точности
+type decimal struct {
+	neg  bool
+	intg string // целая часть без ведущих нулей
+	frac string // дробная часть без завершающих нулей
+}
+
+// parseDecimal разбирает число в начале s так же, как GNU sort -n:
+// необязательный '-', цифры, '.', цифры. Всё, что не число, равно нулю.
+// Возвращает число и остаток строки после него
+func parseDecimal(s string) (decimal, string) {
+	var d decimal
+	pos := 0
+	if pos < len(s) && s[pos] == '-' {
+		d.neg = true
+		pos++
+	}
+	start := pos
+	for pos < len(s) && isDigit(s[pos]) {
+		pos++
+	}
+	d.intg = strings.TrimLeft(s[start:pos], "0")
+	digits := pos > start
+	if pos < len(s) && s[pos] == '.' {
+		pos++
+		fracStart := pos
+		for pos < len(s) && isDigit(s[pos]) {
+			pos++
+		}
+		d.frac = strings.TrimRight(s[fracStart:pos], "0")
+		digits = digits || pos > fracStart
+	}
+	if !digits {
+		return decimal{}, s
+	}
+	if d.intg == "" && d.frac == "" {
+		d.neg = false // -0 равен 0
+	}
+	return d, s[pos:]
+}
+
+func isAlnum(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func (d decimal) isZero() bool {
+	return d.intg == "" && d.frac == ""
+}
+
+func (d decimal) compare(o decimal) int {
+	if d.neg != o.neg {
+		if d.neg {
+			return -1
+		}
+		return 1
+	}
+	res := compareAbs(d, o)
+	if d.neg {
+		res = -res
+	}
+	return res
+}
+
+func compareAbs(a, b decimal) int {
+	if len(a.intg) != len(b.intg) {
+		return compareInts(len(a.intg), len(b.intg))
+	}
+	if res := strings.Compare(a.intg, b.intg); res != 0 {
+		return res
+	}
+	return strings.Compare(a.frac, b.frac)
+}
+
+// unitOrder возвращает порядок суффикса после числа для -h (K=1, M=2, ..., Y=8, как в coreutils 9.1),
+// для отрицательных чисел порядок отрицательный, у нуля суффикс не учитывается
+func unitOrder(num decimal, rest string) int {
+	if num.isZero() || rest == "" {
+		return 0
+	}
+	order := strings.IndexByte("KMGTPEZY", rest[0]) + 1
+	if rest[0] == 'k' {
+		order = 1
+	}
+	if num.neg {
+		return -order
+	}
+	return order
+}
+
+// Вид значения для -g: ошибки разбора идут первыми, затем NaN, затем числа, как в GNU sort
+const (
+	generalInvalid = iota
+	generalNaN
+	generalNumber
+)
+
+// parseGeneral разбирает число в начале ключа как strtold: знак, десятичная или
+// шестнадцатеричная запись с экспонентой, inf, infinity и nan без учёта регистра.
+// Мантисса в 64 бита, как у long double, а диапазон экспоненты шире, поэтому 1e500 - конечное число
+func parseGeneral(text string) (flt *big.Float, neg bool, kind int) {
+	text = strings.TrimLeft(text, " \t\n\v\f\r")
+	pos := 0
+	if pos < len(text) && (text[pos] == '+' || text[pos] == '-') {
+		neg = text[pos] == '-'
+		pos++
+	}
+	rest := strings.ToLower(text[pos:min(len(text), pos+3)])
+	switch rest {
+	case "inf":
+		return new(big.Float).SetInf(neg), neg, generalNumber
+	case "nan":
+		return nil, neg, generalNaN
+	}
+
+	number := floatPrefix(text, pos)
+	if number == "" {
+		return nil, false, generalInvalid
+	}
+	flt, _, err := big.ParseFloat(number, 0, 64, big.ToNearestEven)
+	if err != nil {
+		return nil, false, generalInvalid
+	}
+	return flt, neg, generalNumber
+}
+
+// floatPrefix возвращает самый длинный префикс text, являющийся числом; мантисса начинается с pos.
+// К шестнадцатеричному числу без экспоненты дописывается "p0"
+func floatPrefix(text string, pos int) string {
+	hex := strings.HasPrefix(text[pos:], "0x") || strings.HasPrefix(text[pos:], "0X")
+	isMantissa, expChar, expSuffix := isDigit, byte('e'), ""
+	start := pos
+	if hex {
+		isMantissa, expChar, expSuffix = isHexDigit, 'p', "p0"
+		start = pos + 2
+	}
+
+	end := start
+	for end < len(text) && isMantissa(text[end]) {
+		end++
+	}
+	digits := end - start
+	if end < len(text) && text[end] == '.' {
+		end++
+		fracStart := end
+		for end < len(text) && isMantissa(text[end]) {
+			end++
+		}
+		digits += end - fracStart
+	}
+	if digits == 0 {
+		if hex {
+			// "0x" без цифр - это просто 0
+			return text[:pos+1]
+		}
+		return ""
+	}
+
+	// Экспонента учитывается, только если после неё есть цифры
+	if end < len(text) && (text[end]|0x20) == expChar {
+		exp := end + 1
+		if exp < len(text) && (text[exp] == '+' || text[exp] == '-') {
+			exp++
+		}
+		if exp < len(text) && isDigit(text[exp]) {
+			for exp < len(text) && isDigit(text[exp]) {
+				exp++
+			}
+			return text[:exp]
+		}
+	}
+	return text[:end] + expSuffix
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || (ch|0x20 >= 'a' && ch|0x20 <= 'f')
+}
+
+var monthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+// parseMonth возвращает номер месяца (1-12) по первым трём буквам ключа, 0 для неизвестных
+func parseMonth(text string) int {
+	text = trimBlanks(text)
+	if len(text) < 3 {
+		return 0
+	}
+	prefix := strings.ToUpper(text[:3])
+	for i, name := range monthNames {
+		if prefix == name {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package sortpkg
+
+import (
+	"io"
+
+	"github.com/pozedorum/WB_project_2/task10/pkg/options"
+)
+
+// Config - настройки сортировки без привязки к флагам командной строки
+type Config struct {
+	Global    options.KeyDef   // глобальные модификаторы; ключи без своих модификаторов наследуют их
+	Keys      []options.KeyDef // пустой список - ключом служит вся строка
+	Separator string           // разделитель полей, пустая строка - переход от пробелов к непробельным символам
+	Locale    string           // тег BCP 47, пустая строка - побайтовое сравнение
+	// RandomSeed - ключ хеширования для -R, пустой - случайный для каждой сортировки
+	RandomSeed []byte
+
+	Unique bool
+	Stable bool
+	Merge  bool // входы уже отсортированы, только слить
+	Check  bool // только проверить порядок, вернуть *DisorderError
+	CSV    bool // записи CSV/TSV, Separator - ',' или '\t'
+	Header bool // с CSV первая запись каждого входа - заголовок, выводится один раз в начале
+	// ZeroTerminated - записи завершаются нулевым байтом, а не переводом строки (-z)
+	ZeroTerminated bool
+
+	BufferSize int64 // размер чанка в байтах
+	Parallel   int   // число воркеров, сортирующих чанки
+	BatchSize  int   // сколько файлов сливается за один проход
+	TempDir    string
+
+	// Progress вызывается последовательно (не конкурентно), но из разных горутин
+	Progress func(Progress)
+}
+
+// ConfigFromFlags переводит разобранные флаги командной строки в Config
+func ConfigFromFlags(fs options.FlagStruct) Config {
+	return Config{
+		Global: options.KeyDef{
+			StartField:      1,
+			StartChar:       1,
+			SkipStartBlanks: *fs.BFlag,
+			SkipEndBlanks:   *fs.BFlag,
+			Numeric:         *fs.NFlag,
+			Human:           *fs.HFlag,
+			Month:           *fs.MFlag,
+			Reverse:         *fs.RFlag,
+			Fold:            *fs.FFlag,
+			Dictionary:      *fs.DFlag,
+			General:         *fs.GFlag,
+			Version:         *fs.VFlag,
+			Random:          *fs.RandomFlag,
+		},
+		Keys:           fs.Keys,
+		Separator:      fs.Separator,
+		Locale:         fs.Locale,
+		RandomSeed:     fs.RandomSeed,
+		Unique:         *fs.UFlag,
+		Stable:         *fs.StableFlag,
+		Merge:          *fs.MergeFlag,
+		Check:          *fs.CFlag || *fs.CQuietFlag,
+		CSV:            *fs.CSVFlag || *fs.TSVFlag,
+		Header:         *fs.HeaderFlag,
+		ZeroTerminated: *fs.ZFlag,
+		BufferSize:     fs.BufferSize,
+		Parallel:       fs.Parallel,
+		BatchSize:      *fs.BatchSizeFlag,
+		TempDir:        *fs.TempDirFlag,
+	}
+}
+
+// eol возвращает признак конца записи
+func (cfg Config) eol() string {
+	if cfg.ZeroTerminated {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// Stage - этап сортировки для Progress
+type Stage int
+
+const (
+	StageSort  Stage = iota // чтение входа и сортировка чанков
+	StageMerge              // слияние отсортированных файлов
+	StageDone
+)
+
+// Progress описывает ход сортировки
+type Progress struct {
+	Stage        Stage
+	BytesRead    int64
+	LinesRead    int64
+	Chunks       int // прочитано чанков
+	ChunksSorted int
+	MergePass    int // номер прохода слияния, с 1
+	Runs         int // сколько файлов сливается на текущем проходе
+	LinesWritten int64
+}
+
+// Input - вход сортировки. Open вызывается не раньше, чем вход понадобится,
+// чтобы при слиянии многих файлов не держать их все открытыми
+type Input struct {
+	Name string // имя для сообщений -c
+	Open func() (io.ReadCloser, error)
+
+	header bool // первая запись - заголовок CSV; только у входов пользователя, не у временных файлов
+}
+
+// FileInput открывает файл по имени, "-" означает stdin
+func FileInput(name string) Input {
+	return Input{Name: name, Open: func() (io.ReadCloser, error) { return openInput(name) }}
+}
+
+// ReaderInput оборачивает уже открытый io.Reader
+func ReaderInput(name string, r io.Reader) Input {
+	return Input{Name: name, Open: func() (io.ReadCloser, error) { return io.NopCloser(r), nil }}
+}
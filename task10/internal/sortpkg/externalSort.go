@@ -4,172 +4,403 @@ package sortpkg
 import (
 	"bufio"
 	"container/heap"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"slices"
+	"sync"
 
 	"github.com/pozedorum/WB_project_2/task10/pkg/options"
 )
 
-const ConstChunkSize = 10_000 // Чанки по 10k строк (тут я не знаю, насколько ограничивать размер чанка, потом подумаю)
+// ctxCheckLines - как часто (в строках) проверяется отмена контекста
+const ctxCheckLines = 1024
 
 type ExternalSortStruct struct {
-	fs            options.FlagStruct
-	tempFilesList []string
-	inputFile     string
-	outputFile    string
-	chunkSize     int
+	ctx           context.Context
+	cfg           Config
+	cmp           *Comparator
+	tempFilesList []Input // отсортированные данные для слияния в порядке входа
+	inputs        []Input
+	out           io.Writer
+	created       []string // все созданные временные файлы, для очистки
+	header        string   // заголовок CSV первого входа
+	hasHeader     bool
+
+	progressMu sync.Mutex
+	progress   Progress
+}
+
+func MakeExternalSortStruct(ctx context.Context, cfg Config, inputs []Input, out io.Writer) *ExternalSortStruct {
+	cfg.Parallel = max(cfg.Parallel, 1)
+	cfg.BatchSize = max(cfg.BatchSize, 2)
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = options.DefaultBufferSize
+	}
+	return &ExternalSortStruct{
+		ctx:           ctx,
+		cfg:           cfg,
+		cmp:           NewComparator(cfg),
+		tempFilesList: make([]Input, 0),
+		inputs:        inputs,
+		out:           out,
+	}
 }
 
-func MakeExternalSortStruct(fs options.FlagStruct, inputFile, outputFile string, chunkSize int) *ExternalSortStruct {
-	return &ExternalSortStruct{fs, make([]string, 0), inputFile, outputFile, chunkSize}
+// ExternalSort сортирует входные файлы как один набор данных и пишет результат в outputFile.
+// С -m входные файлы считаются уже отсортированными и только сливаются.
+// С -c/-C ничего не пишет и возвращает *DisorderError, если вход не отсортирован.
+// outputFile заменяется атомарно (см. writeFileAtomic), поэтому может быть и одним из входов
+func ExternalSort(inputFiles []string, outputFile string, fs options.FlagStruct) error {
+	inputs := make([]Input, len(inputFiles))
+	for i, name := range inputFiles {
+		inputs[i] = FileInput(name)
+	}
+	return writeFileAtomic(outputFile, func(w io.Writer) error {
+		return Sort(context.Background(), ConfigFromFlags(fs), inputs, w)
+	})
 }
 
-func ExternalSort(inputFile, outputFile string, fs options.FlagStruct) error {
-	ess := MakeExternalSortStruct(fs, inputFile, outputFile, ConstChunkSize)
-	if *ess.fs.CFlag {
-		if isSorted(ess.inputFile, fs) {
-			fmt.Println("File is sorted")
-			return nil
-		} else {
-			fmt.Println("File is not sorted")
-			return nil
+// Sort сортирует входы как один набор данных и пишет результат в w.
+// При отмене ctx возвращает ctx.Err(), временные файлы удаляются в любом случае
+func Sort(ctx context.Context, cfg Config, inputs []Input, w io.Writer) error {
+	inputs = slices.Clone(inputs)
+	for i := range inputs {
+		inputs[i].header = cfg.CSV && cfg.Header
+	}
+	ess := MakeExternalSortStruct(ctx, cfg, inputs, w)
+	if cfg.Check {
+		if len(inputs) > 1 {
+			return fmt.Errorf("extra operand '%s' not allowed with -c", inputs[1].Name)
 		}
+		return ess.checkSorted(inputs[0])
 	}
 
-	err := ess.splitAndSort()
-	if err != nil {
+	defer ess.cleanup()
+	if cfg.Merge {
+		ess.tempFilesList = append(ess.tempFilesList, inputs...)
+	} else if err := ess.splitAndSort(); err != nil {
+		return err
+	}
+	if err := ess.mergeChunks(); err != nil {
 		return err
 	}
-	return ess.mergeChunks()
+	ess.report(func(p *Progress) { p.Stage = StageDone })
+	return nil
 }
 
-func (ess *ExternalSortStruct) splitAndSort() error {
-	file, err := os.Open(ess.inputFile)
+// report обновляет состояние прогресса и передаёт его в Config.Progress
+func (ess *ExternalSortStruct) report(update func(p *Progress)) {
+	if ess.cfg.Progress == nil {
+		return
+	}
+	ess.progressMu.Lock()
+	defer ess.progressMu.Unlock()
+	update(&ess.progress)
+	ess.cfg.Progress(ess.progress)
+}
+
+// openRecords открывает вход. У входа пользователя с заголовком CSV заголовок
+// сразу читается: первый из них запоминается, по нему находятся столбцы ключей,
+// заголовки остальных входов должны с ним совпадать
+func (ess *ExternalSortStruct) openRecords(input Input) (io.ReadCloser, recordScanner, error) {
+	f, err := input.Open()
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	scanner := newRecordScanner(ess.cfg, f)
+	if input.header && scanner.Scan() {
+		header := scanner.Text()
+		sep := ess.cfg.Separator[0]
+		if !ess.hasHeader {
+			ess.header, ess.hasHeader = header, true
+			err = ess.cmp.resolveColumns(splitRecord(header, sep))
+		} else if !slices.Equal(splitRecord(header, sep), splitRecord(ess.header, sep)) {
+			// Столбцы ключей найдены по первому заголовку и в этом входе могут быть другими
+			err = fmt.Errorf("%s: header %q differs from the first input header %q", input.Name, header, ess.header)
+		}
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
 	}
-	defer file.Close()
+	return f, scanner, nil
+}
 
-	scanner := bufio.NewScanner(file)
-	buffer := make([]string, 0, ess.chunkSize)
-	buflen := 0
+// newTempFile создаёт временный файл в каталоге -T
+func (ess *ExternalSortStruct) newTempFile() (*os.File, error) {
+	f, err := createTemp(ess.cfg.TempDir, "sort_chunk_*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	ess.created = append(ess.created, f.Name())
+	return f, nil
+}
 
-	fileInd := 0
-	for scanner.Scan() {
-		buffer = append(buffer, scanner.Text())
-		buflen++
-		if buflen >= ess.chunkSize {
-			ess.sortAndSaveChunk(fileInd, buffer)
-			fileInd++
-			buffer = buffer[:0] // очищаем буфер
-			buflen = 0
+// cleanup удаляет оставшиеся временные файлы, в том числе после ошибки
+func (ess *ExternalSortStruct) cleanup() {
+	for _, name := range ess.created {
+		removeTemp(name)
+	}
+	ess.created = nil
+}
+
+// splitAndSort читает вход чанками по BufferSize байт и сортирует их пулом из Parallel воркеров.
+// Чтение ждёт свободного воркера, поэтому в памяти не больше Parallel+1 чанков.
+// Файлы чанков создаются по порядку чтения, так что результат не зависит от числа воркеров
+func (ess *ExternalSortStruct) splitAndSort() error {
+	type chunk struct {
+		file  *os.File
+		lines []string
+	}
+	chunks := make(chan chunk)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
 		}
 	}
-	if len(buffer) > 0 {
-		ess.sortAndSaveChunk(fileInd, buffer)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for range ess.cfg.Parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ch := range chunks {
+				if err := ess.ctx.Err(); err != nil {
+					ch.file.Close()
+					setErr(err)
+					continue
+				}
+				if err := ess.sortAndSaveChunk(ch.file, ch.lines); err != nil {
+					setErr(err)
+					continue
+				}
+				ess.report(func(p *Progress) { p.ChunksSorted++ })
+			}
+		}()
 	}
 
-	return nil
+	send := func(lines []string, size int64) error {
+		f, err := ess.newTempFile()
+		if err != nil {
+			return err
+		}
+		ess.tempFilesList = append(ess.tempFilesList, FileInput(f.Name()))
+		ess.report(func(p *Progress) {
+			p.Chunks++
+			p.LinesRead += int64(len(lines))
+			p.BytesRead += size
+		})
+		chunks <- chunk{f, lines}
+		return nil
+	}
+
+	// Все входы читаются как один поток, чанк может содержать строки из разных файлов
+	var buffer []string
+	var bufSize int64
+	readInput := func(input Input) error {
+		file, scanner, err := ess.openRecords(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		for lines := 1; !failed() && scanner.Scan(); lines++ {
+			if lines%ctxCheckLines == 0 {
+				if err := ess.ctx.Err(); err != nil {
+					return err
+				}
+			}
+			line := scanner.Text()
+			// В чанке всегда есть хотя бы одна строка, даже если она длиннее чанка
+			if len(buffer) > 0 && bufSize+int64(len(line))+1 > ess.cfg.BufferSize {
+				if err := send(buffer, bufSize); err != nil {
+					return err
+				}
+				buffer = nil // буфер теперь принадлежит воркеру
+				bufSize = 0
+			}
+			buffer = append(buffer, line)
+			bufSize += int64(len(line)) + 1
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %w", input.Name, err)
+		}
+		return nil
+	}
+
+	var readErr error
+	for _, input := range ess.inputs {
+		if readErr = readInput(input); readErr != nil {
+			break
+		}
+	}
+	if readErr == nil && len(buffer) > 0 && !failed() {
+		readErr = send(buffer, bufSize)
+	}
+	close(chunks)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return readErr
+}
+
+// openInput открывает входной файл, "-" означает stdin
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
 }
 
-func (ess *ExternalSortStruct) sortAndSaveChunk(ind int, lines []string) {
-	ss := MakeSortStruct(lines, ess.fs)
+func (ess *ExternalSortStruct) sortAndSaveChunk(f *os.File, lines []string) error {
+	ss := MakeSortStruct(lines, ess.cmp)
 
 	ss.StringsSort()
 
-	tmpFile := "chunk_" + strconv.Itoa(ind) + ".tmp"
-	f, _ := os.Create(tmpFile)
-	defer f.Close()
-
+	writer := bufio.NewWriter(f)
 	for _, line := range ss.lines {
-		if _, err := f.WriteString(line + "\n"); err != nil {
-			fmt.Printf("ExternalSortStruct.sortAndSaveChunk - file.WriteString: %v", err)
+		if _, err := writer.WriteString(line + ess.cfg.eol()); err != nil {
+			f.Close()
+			return fmt.Errorf("ExternalSortStruct.sortAndSaveChunk - writer.WriteString: %w", err)
 		}
 	}
-
-	ess.tempFilesList = append(ess.tempFilesList, tmpFile)
+	if err := writer.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
+// mergeChunks сливает отсортированные чанки. Если их больше BatchSize,
+// слияние идёт в несколько проходов: соседние группы по BatchSize файлов
+// сливаются в промежуточные файлы, пока не останется не больше BatchSize
 func (ess *ExternalSortStruct) mergeChunks() error {
-	out, err := os.Create(ess.outputFile)
-	if err != nil {
-		return err
+	runs := ess.tempFilesList
+	batchSize := ess.cfg.BatchSize
+	for pass := 1; ; pass++ {
+		ess.report(func(p *Progress) {
+			p.Stage = StageMerge
+			p.MergePass = pass
+			p.Runs = len(runs)
+		})
+		if len(runs) <= batchSize {
+			break
+		}
+
+		next := make([]Input, 0, (len(runs)+batchSize-1)/batchSize)
+		for start := 0; start < len(runs); start += batchSize {
+			batch := runs[start:min(start+batchSize, len(runs))]
+			f, err := ess.newTempFile()
+			if err != nil {
+				return err
+			}
+			_, err = ess.mergeRuns(batch, f, false)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			next = append(next, FileInput(f.Name()))
+		}
+		runs = next
 	}
-	writer := bufio.NewWriter(out)
-	defer func() {
-		writer.Flush()
-		out.Close()
-	}()
+
+	written, err := ess.mergeRuns(runs, ess.out, true)
+	ess.report(func(p *Progress) { p.LinesWritten = written })
+	ess.tempFilesList = ess.tempFilesList[:0]
+	return err
+}
+
+// mergeRuns сливает отсортированные входы в w через кучу, удаляет временные файлы среди них
+// и возвращает число записанных строк. Входы пользователя (в режиме -m) не удаляются.
+// Порядок runs важен: при равных строках раньше идёт строка из входа с меньшим индексом.
+// В итоговый вывод (final) первым пишется заголовок CSV
+func (ess *ExternalSortStruct) mergeRuns(runs []Input, w io.Writer, final bool) (int64, error) {
+	writer := bufio.NewWriter(w)
 
 	// Открываем все чанки
-	files := make([]*os.File, len(ess.tempFilesList))
-	scanners := make([]*bufio.Scanner, len(ess.tempFilesList))
-	for i, chunk := range ess.tempFilesList {
-		f, err := os.Open(chunk)
+	files := make([]io.ReadCloser, 0, len(runs))
+	defer func() {
+		for i, f := range files {
+			f.Close()
+			removeTemp(runs[i].Name)
+		}
+	}()
+	scanners := make([]recordScanner, len(runs))
+	for i, run := range runs {
+		f, scanner, err := ess.openRecords(run)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		files = append(files, f)
+		scanners[i] = scanner
+	}
+	if final && ess.hasHeader {
+		if _, err := writer.WriteString(ess.header + ess.cfg.eol()); err != nil {
+			return 0, err
 		}
-		files[i] = f
-		scanners[i] = bufio.NewScanner(f)
 	}
 
 	// Инициализация кучи
-	h := &MinHeap{}
+	h := &MinHeap{cmp: ess.cmp}
 	heap.Init(h)
 
 	// Загружаем первые строки каждого файла
 	for i, sc := range scanners {
 		if sc.Scan() {
-			heap.Push(h, HeapItem{line: sc.Text(), index: i})
+			heap.Push(h, HeapItem{line: ess.cmp.makeKeyed(sc.Text()), index: i})
 		}
 	}
 
-	var lastLine string
-	firstLine := true
+	var last keyedLine
+	var written int64
 
 	for h.Len() > 0 {
 		item := heap.Pop(h).(HeapItem)
 
-		if *ess.fs.UFlag {
-			if firstLine {
-				lastLine = item.line
-
-				if _, err := writer.WriteString(item.line + "\n"); err != nil {
-					fmt.Printf("ExternalSortStruct.mergeChunks - writer.WriteString(1): %v", err)
-				}
-				firstLine = false
-			} else if getKey(ess.fs, item.line) != getKey(ess.fs, lastLine) {
-
-				lastLine = item.line
-
-				if _, err := writer.WriteString(item.line + "\n"); err != nil {
-					fmt.Printf("ExternalSortStruct.mergeChunks - writer.WriteString(2): %v", err)
-				}
+		// С -u выводим только первую строку из равных по ключу
+		if !ess.cfg.Unique || written == 0 || ess.cmp.compareKeys(item.line, last) != 0 {
+			if _, err := writer.WriteString(item.line.line + ess.cfg.eol()); err != nil {
+				return written, fmt.Errorf("ExternalSortStruct.mergeRuns - writer.WriteString: %w", err)
 			}
-		} else {
-			if _, err := writer.WriteString(item.line + "\n"); err != nil {
-				fmt.Printf("ExternalSortStruct.mergeChunks - writer.WriteString(3): %v", err)
+			last = item.line
+			written++
+			if written%ctxCheckLines == 0 {
+				if err := ess.ctx.Err(); err != nil {
+					return written, err
+				}
 			}
 		}
 
 		// Продвигаем сканер и добавляем следующую строку в кучу
 		if scanners[item.index].Scan() {
-			nextLine := scanners[item.index].Text()
-			if !(*ess.fs.UFlag && nextLine == lastLine) {
-				heap.Push(h, HeapItem{
-					line:  nextLine,
-					index: item.index,
-				})
-			}
+			heap.Push(h, HeapItem{
+				line:  ess.cmp.makeKeyed(scanners[item.index].Text()),
+				index: item.index,
+			})
 		}
 	}
 
-	// Закрытие файлов
-	for _, f := range files {
-		f.Close()
-		os.Remove(f.Name())
+	for _, sc := range scanners {
+		if err := sc.Err(); err != nil {
+			return written, err
+		}
 	}
-	//fmt.Printf("result of sort locales by path: %s\n", ess.outputFile)
-	return nil
+	return written, writer.Flush()
 }
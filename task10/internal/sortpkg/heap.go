@@ -1,21 +1,31 @@
 package sortpkg
 
 type HeapItem struct {
-	line  string // Текущая строка
-	index int    // Индекс сканера (0..len(scanners)-1)
+	line  keyedLine // Текущая строка с разобранными ключами
+	index int       // Индекс сканера (0..len(scanners)-1)
 }
 
-type MinHeap []HeapItem
+// MinHeap упорядочивает строки компаратором, при равенстве - по номеру чанка,
+// чтобы слияние было устойчивым
+type MinHeap struct {
+	items []HeapItem
+	cmp   *Comparator
+}
 
-func (h MinHeap) Len() int           { return len(h) }
-func (h MinHeap) Less(i, j int) bool { return h[i].line < h[j].line }
-func (h MinHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h MinHeap) Len() int { return len(h.items) }
+func (h MinHeap) Less(i, j int) bool {
+	if res := h.cmp.compare(h.items[i].line, h.items[j].line); res != 0 {
+		return res < 0
+	}
+	return h.items[i].index < h.items[j].index
+}
+func (h MinHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
 
-func (h *MinHeap) Push(x any) { *h = append(*h, x.(HeapItem)) }
+func (h *MinHeap) Push(x any) { h.items = append(h.items, x.(HeapItem)) }
 func (h *MinHeap) Pop() any {
-	old := *h
+	old := h.items
 	n := len(old)
 	x := old[n-1]
-	*h = old[:n-1]
+	h.items = old[:n-1]
 	return x
 }
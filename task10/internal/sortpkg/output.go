@@ -0,0 +1,58 @@
+package sortpkg
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic пишет результат write во временный файл рядом с name и переименовывает его в name.
+// Пока write читает входы, name не меняется, поэтому он может быть и одним из входов,
+// а при ошибке или прерывании прежнее содержимое сохраняется.
+// Права существующего файла переносятся на новый, новый файл создаётся с 0666 с учётом umask.
+// Если name - не обычный файл (например, /dev/null), он перезаписывается на месте
+func writeFileAtomic(name string, write func(w io.Writer) error) error {
+	// Заменяем файл, на который указывает ссылка, а не саму ссылку
+	if target, err := filepath.EvalSymlinks(name); err == nil {
+		name = target
+	}
+	info, statErr := os.Stat(name)
+	if statErr == nil && !info.Mode().IsRegular() {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			return err
+		}
+		err = write(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	perm := os.FileMode(0o666)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+	f, err := createTempPerm(filepath.Dir(name), "."+filepath.Base(name)+".sort-", perm)
+	if err != nil {
+		return err
+	}
+	// После переименования удалять уже нечего, removeTemp только снимет файл с учёта
+	defer removeTemp(f.Name())
+
+	err = write(f)
+	if err == nil && statErr == nil {
+		// umask мог урезать права при создании
+		err = f.Chmod(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky))
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,161 @@
+package sortpkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// recordScanner читает вход по записям: строкам или записям CSV/TSV.
+// bufio.Scanner уже реализует этот интерфейс
+type recordScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+func newRecordScanner(cfg Config, r io.Reader) recordScanner {
+	if cfg.CSV && cfg.Separator == "," {
+		return newCSVScanner(r, cfg.Separator[0])
+	}
+	scanner := bufio.NewScanner(r)
+	switch {
+	case cfg.ZeroTerminated:
+		scanner.Split(scanRecords(0))
+	case cfg.CSV:
+		// В TSV нет кавычек, запись - строка; '\r' у CRLF сохраняется, чтобы вывод совпал с входом
+		scanner.Split(scanRecords('\n'))
+	}
+	return scanner
+}
+
+// scanRecords возвращает bufio.SplitFunc для записей, завершённых байтом term
+// (нулевым для -z). Последняя запись может быть без завершающего байта
+func scanRecords(term byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, term); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// csvScanner читает записи CSV через encoding/csv и отдаёт их исходные байты без
+// завершающего '\n': кавычки и '\r' у CRLF не меняются, поля для ключей разбирает splitRecord.
+// Запись может содержать переводы строк внутри кавычек, поэтому временные файлы
+// в этом режиме тоже читаются csvScanner
+type csvScanner struct {
+	r    *csv.Reader
+	raw  *rawRecorder
+	base int64 // смещение raw.buf[0] во входе
+	text string
+	err  error
+	done bool
+}
+
+// rawRecorder запоминает байты, прочитанные csv.Reader, пока их не заберёт Scan
+type rawRecorder struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (rr *rawRecorder) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	rr.buf = append(rr.buf, p[:n]...)
+	return n, err
+}
+
+func newCSVScanner(r io.Reader, sep byte) *csvScanner {
+	sc := &csvScanner{raw: &rawRecorder{r: r}}
+	sc.r = csv.NewReader(sc.raw)
+	sc.r.Comma = rune(sep)
+	sc.r.FieldsPerRecord = -1
+	sc.r.ReuseRecord = true
+	return sc
+}
+
+func (sc *csvScanner) Scan() bool {
+	if sc.done {
+		return false
+	}
+	// Разобранные поля не нужны: Read только проверяет формат и находит конец записи
+	if _, err := sc.r.Read(); err != nil {
+		sc.done = true
+		if err != io.EOF {
+			sc.err = err
+		}
+		return false
+	}
+
+	end := sc.r.InputOffset()
+	raw := sc.raw.buf[:end-sc.base]
+	// csv.Reader пропускает пустые строки перед записью
+	for {
+		if rest, ok := bytes.CutPrefix(raw, []byte("\n")); ok {
+			raw = rest
+		} else if rest, ok := bytes.CutPrefix(raw, []byte("\r\n")); ok {
+			raw = rest
+		} else {
+			break
+		}
+	}
+	sc.text = string(bytes.TrimSuffix(raw, []byte("\n")))
+	sc.raw.buf = sc.raw.buf[:copy(sc.raw.buf, sc.raw.buf[end-sc.base:])]
+	sc.base = end
+	return true
+}
+
+func (sc *csvScanner) Text() string { return sc.text }
+
+func (sc *csvScanner) Err() error { return sc.err }
+
+// splitRecord разбирает на поля запись CSV, прочитанную csvScanner, или строку TSV,
+// поля получаются такими же, как у csv.Reader
+func splitRecord(record string, sep byte) []string {
+	record = strings.TrimSuffix(record, "\r")
+	if sep == '\t' {
+		return strings.Split(record, "\t")
+	}
+	fields := make([]string, 0, 8)
+	for {
+		if strings.HasPrefix(record, `"`) {
+			var sb strings.Builder
+			i := 1
+			for i < len(record) {
+				if record[i] == '"' {
+					if i+1 < len(record) && record[i+1] == '"' {
+						sb.WriteByte('"')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				// csv.Reader заменяет CRLF внутри кавычек на '\n'
+				if record[i] == '\r' && i+1 < len(record) && record[i+1] == '\n' {
+					i++
+				}
+				sb.WriteByte(record[i])
+				i++
+			}
+			fields = append(fields, sb.String())
+			record = record[i:]
+		} else {
+			i := strings.IndexByte(record, sep)
+			if i < 0 {
+				return append(fields, record)
+			}
+			fields = append(fields, record[:i])
+			record = record[i:]
+		}
+		if record == "" {
+			return fields
+		}
+		record = record[1:] // разделитель
+	}
+}
@@ -1,179 +1,83 @@
 package sortpkg
 
 import (
-	"bufio"
 	"fmt"
-	"log"
-	"os"
-	"sort"
-	"strconv"
-	"strings"
-
-	"github.com/pozedorum/WB_project_2/task10/pkg/options"
+	"slices"
 )
 
-var monthOrder map[string]int
-
-func init() {
-	monthOrder = map[string]int{
-		"jan": 1, "january": 1, "feb": 2, "february": 2,
-		"mar": 3, "march": 3, "apr": 4, "april": 4,
-		"may": 5, "jun": 6, "june": 6, "jul": 7, "july": 7,
-		"aug": 8, "august": 8, "sep": 9, "september": 9,
-		"oct": 10, "october": 10, "nov": 11, "november": 11,
-		"dec": 12, "december": 12,
-	}
-}
-
 type SortStruct struct {
 	lines []string
-	fs    options.FlagStruct
+	cmp   *Comparator
 }
 
-func MakeSortStruct(lines []string, fs options.FlagStruct) *SortStruct {
-	return &SortStruct{lines, fs}
+func MakeSortStruct(lines []string, cmp *Comparator) *SortStruct {
+	return &SortStruct{lines, cmp}
 }
 
-// res1 := getKey(ss.fs, ss.lines[i])
-// 		res2 := getKey(ss.fs, ss.lines[j])
-
+// StringsSort сортирует строки компаратором, с -u оставляет первую из равных по ключу
 func (ss *SortStruct) StringsSort() {
-	sort.SliceStable(ss.lines, func(i, j int) bool {
-		res1 := getKey(ss.fs, ss.lines[i])
-		res2 := getKey(ss.fs, ss.lines[j])
-		if res1 == "0" {
-			return true
-		}
-		if res2 == "0" {
-			return false
-		}
-		if *ss.fs.RFlag {
-			return res1 >= res2
-		}
-		return res1 < res2 // Устойчивая сортировка
-	})
-}
-
-// getKey возвращает ключ для сортировки на основе флагов
-// Формат ключа:
-//   - Числа: 20-значное число с ведущими нулями
-//   - Месяцы: двузначный номер месяца (01-12)
-//   - Ошибка: если флаги конфликтуют
-//   - "0", если число 0, или строка без возможности сортировки
-
-func getKey(fs options.FlagStruct, str string) string {
-	var resPart string
-	parts := strings.Fields(str)
-
-	if *fs.KFlag < 1 || *fs.KFlag > len(parts) { // k flag -- sort by column number N
-		return "0"
-	} else {
-		resPart = parts[*fs.KFlag-1]
-	}
-
-	if *fs.BFlag { // b flag -- ignore trailing blanks
-		resPart = strings.TrimSpace(resPart)
-	}
-
-	if (*fs.HFlag || *fs.NFlag) && *fs.MFlag {
-		panic("flags -n/-h and -m are mutually exclusive")
-	}
-
-	switch {
-	case *fs.HFlag:
-		return parseHumanNumber(resPart)
-	case *fs.NFlag:
-		return parseNumber(resPart)
-	case *fs.MFlag:
-		return parseMonth(resPart)
-	default:
-		return resPart
+	keyed := make([]keyedLine, len(ss.lines))
+	for i, line := range ss.lines {
+		keyed[i] = ss.cmp.makeKeyed(line)
 	}
-}
+	slices.SortStableFunc(keyed, ss.cmp.compare) // Устойчивая сортировка
 
-func parseNumber(resPart string) string {
-	if num, err := strconv.ParseFloat(resPart, 64); err != nil {
-		return "0"
-	} else {
-		return fmt.Sprintf("%020.0f", num)
+	ss.lines = ss.lines[:0]
+	for i, kl := range keyed {
+		if ss.cmp.unique && i > 0 && ss.cmp.compareKeys(keyed[i-1], kl) == 0 {
+			continue
+		}
+		ss.lines = append(ss.lines, kl.line)
 	}
 }
 
-func parseHumanNumber(resPart string) string {
-	if len(resPart) == 0 {
-		return "0"
-	}
-	numStr := resPart
-	multiplier := 1.0
-	lastChar := strings.ToUpper(resPart[len(resPart)-1:])
-	if strings.ContainsAny(lastChar, "KMGT") {
-		numStr = strings.TrimRight(resPart, "KMGT")
-		switch lastChar {
-		case "K":
-			multiplier = 1e3
-		case "M":
-			multiplier = 1e6
-		case "G":
-			multiplier = 1e9
-		case "T":
-			multiplier = 1e12
-		}
-	}
-	num, err := strconv.ParseFloat(numStr, 64)
-	if err != nil {
-		return "0"
-	}
-	return fmt.Sprintf("%020.0f", num*multiplier)
+// DisorderError сообщает о первой строке, нарушающей порядок, при проверке -c/-C
+type DisorderError struct {
+	File string
+	Line int // номер строки, с 1
+	Text string
 }
 
-func parseMonth(resPart string) string {
-	if num, ok := monthOrder[strings.ToLower(resPart)]; ok {
-		return fmt.Sprintf("%02d", num) // Форматируем как 01, 02, ... 12
-	} else {
-		return "0"
-	}
+func (e *DisorderError) Error() string {
+	return fmt.Sprintf("%s:%d: disorder: %s", e.File, e.Line, e.Text)
 }
 
-// log.Printf("warning: -c %s file is empty", filepath)
-func isSorted(filepath string, fs options.FlagStruct) bool {
-	file, err := os.Open(filepath)
+// checkSorted проверяет, что вход отсортирован, и возвращает *DisorderError для первой строки не по порядку
+func (ess *ExternalSortStruct) checkSorted(input Input) error {
+	file, scanner, err := ess.openRecords(input)
 	if err != nil {
-		log.Printf("Error opening file: %v", err)
-		return false
+		return err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	first := 1
+	if ess.hasHeader {
+		first++ // заголовок не проверяется
+	}
 	if !scanner.Scan() {
 		// Пустой файл считается отсортированным
-		return true
+		return scanner.Err()
 	}
 
-	prevLine := scanner.Text()
-	prevKey := getKey(fs, prevLine)
+	cmp := ess.cmp
+	prev := cmp.makeKeyed(scanner.Text())
 
-	for scanner.Scan() {
-		currentLine := scanner.Text()
-		currentKey := getKey(fs, currentLine)
-
-		// Сравниваем ключи с учетом флага -r
-		if *fs.RFlag {
-			if currentKey > prevKey {
-				return false
-			}
-		} else {
-			if currentKey < prevKey {
-				return false
+	for lineNum := first + 1; scanner.Scan(); lineNum++ {
+		if lineNum%ctxCheckLines == 0 {
+			if err := ess.ctx.Err(); err != nil {
+				return err
 			}
 		}
+		current := cmp.makeKeyed(scanner.Text())
 
-		prevKey = currentKey
-	}
+		// С -u равные строки тоже считаются нарушением порядка
+		res := cmp.compare(prev, current)
+		if res > 0 || (cmp.unique && res == 0) {
+			return &DisorderError{File: input.Name, Line: lineNum, Text: current.line}
+		}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading file: %v", err)
-		return false
+		prev = current
 	}
 
-	return true
+	return scanner.Err()
 }
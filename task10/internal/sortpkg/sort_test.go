@@ -0,0 +1,646 @@
+package sortpkg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pozedorum/WB_project_2/task10/pkg/options"
+)
+
+const goldenDir = "../../tests/golden"
+
+type goldenCase struct {
+	name   string
+	flags  []string
+	inputs []string
+}
+
+// readGoldenCases читает cases.txt; ожидаемые результаты получены GNU sort с LC_ALL=C
+func readGoldenCases(t *testing.T) []goldenCase {
+	t.Helper()
+	f, err := os.Open(filepath.Join(goldenDir, "cases.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var cases []goldenCase
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, "|")
+		if len(parts) != 3 {
+			t.Fatalf("bad case line %q", line)
+		}
+		var flags []string
+		if parts[1] != "-" {
+			flags = strings.Fields(parts[1])
+		}
+		var inputs []string
+		for _, input := range strings.Fields(parts[2]) {
+			inputs = append(inputs, filepath.Join(goldenDir, input))
+		}
+		cases = append(cases, goldenCase{parts[0], flags, inputs})
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return cases
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range readGoldenCases(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			fs, _, err := options.ParseArgs(tc.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join(goldenDir, tc.name+".expected"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Маленькие чанки, чтобы проверить и сортировку, и слияние через кучу,
+			// при любом числе воркеров результат должен совпадать побайтово
+			for _, chunkSize := range []int64{options.DefaultBufferSize, 16} {
+				for _, parallel := range []int{1, 4} {
+					fs.BufferSize, fs.Parallel = chunkSize, parallel
+					tmpDir := t.TempDir()
+					*fs.TempDirFlag, *fs.BatchSizeFlag = tmpDir, 2 // несколько проходов слияния
+					out := filepath.Join(t.TempDir(), "out.txt")
+					if err := ExternalSort(tc.inputs, out, *fs); err != nil {
+						t.Fatal(err)
+					}
+					assertEmptyDir(t, tmpDir)
+					for _, input := range tc.inputs {
+						if _, err := os.Stat(input); err != nil {
+							t.Fatalf("input file removed: %v", err)
+						}
+					}
+					got, err := os.ReadFile(out)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if string(got) != string(want) {
+						t.Errorf("chunk size %d, parallel %d:\ngot:\n%s\nwant:\n%s", chunkSize, parallel, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestCompareNumeric(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"10", "9", 1},
+		{"-10", "-9", -1},
+		{"-0", "0", 0},
+		{"007", "7", 0},
+		{"3.14", "3.140", 0},
+		{".5", "0.5", 0},
+		{"abc", "0", 0},
+		{"123456789012345678901234567890", "123456789012345678901234567891", -1},
+		{"99999999999999999999", "100000000000000000000", -1},
+		{"-1.5", "-1.25", -1},
+	}
+	for _, tt := range tests {
+		a, _ := parseDecimal(tt.a)
+		b, _ := parseDecimal(tt.b)
+		if got := a.compare(b); got != tt.want {
+			t.Errorf("compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseArgsIncompatible(t *testing.T) {
+	for _, args := range [][]string{{"-n", "-M"}, {"-h", "-n"}, {"-k", "0"}} {
+		if _, _, err := options.ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	rnd := rand.New(rand.NewPCG(1, 2))
+	var sb strings.Builder
+	for range 5000 {
+		fmt.Fprintf(&sb, "%d %s %d\n", rnd.IntN(100), strings.Repeat("x", rnd.IntN(5)), rnd.IntN(1000))
+	}
+	if err := os.WriteFile(input, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"-n"}, {"-k", "2,2", "-k", "3n"}, {"-u", "-k", "1,1n"}} {
+		var outputs []string
+		for _, parallel := range []string{"1", "8"} {
+			fs, _, err := options.ParseArgs(append([]string{"-S", "1K", "--parallel", parallel}, args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(dir, "out"+parallel+".txt")
+			if err := ExternalSort([]string{input}, out, *fs); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputs = append(outputs, string(data))
+		}
+		if outputs[0] != outputs[1] {
+			t.Errorf("%v: parallel output differs from sequential", args)
+		}
+	}
+}
+
+func TestRandomSort(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	var sb strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&sb, "%c %d\n", 'a'+i%26, i)
+	}
+	if err := os.WriteFile(input, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"seed1", "seed2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Repeat(name, 4)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		fs, _, err := options.ParseArgs(append([]string{"-T", dir, "-k", "1,1R"}, args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, "out.txt")
+		if err := ExternalSort([]string{input}, out, *fs); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	seed1 := filepath.Join(dir, "seed1")
+	got := run("--random-source", seed1)
+	// Чанки по 1K и параллельная сортировка дают тот же порядок групп
+	if external := run("--random-source", seed1, "-S", "1K", "--parallel", "4", "--batch-size", "2"); external != got {
+		t.Error("external sort output differs from in-memory")
+	}
+	if other := run("--random-source", filepath.Join(dir, "seed2")); other == got {
+		t.Error("different seeds give the same order")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 3000 {
+		t.Fatalf("got %d lines, want 3000", len(lines))
+	}
+	seen := map[byte]bool{}
+	for i, line := range lines {
+		if i > 0 && line[0] != lines[i-1][0] {
+			if seen[line[0]] {
+				t.Fatalf("group %q is split", line[0])
+			}
+		}
+		seen[line[0]] = true
+		// Внутри группы строки упорядочены последним сравнением целых строк
+		if i > 0 && line[0] == lines[i-1][0] && line < lines[i-1] {
+			t.Errorf("lines %q and %q are out of order", lines[i-1], line)
+		}
+	}
+	if slices.IsSorted(lines) {
+		t.Error("random sort returned bytewise order")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	prices := write("prices.csv", "name,price\n\"Smith, J\",10\n\"multi\nline\",2.5\nb,-1\n")
+	more := write("more.csv", "name,price\n\"say \"\"hi\"\"\",3\n")
+	tsv := write("prices.tsv", "name\tprice\nb\t2\na\t10\n")
+	crlf := write("crlf.csv", "name,price\r\n\"a\",2\r\n b ,1\r\n\"c\r\nd\",3\r\n")
+	quotes := write("quotes.tsv", "\"x\ty\"\t2\nb\"c\t\"1\"\n")
+
+	tests := []struct {
+		name   string
+		args   []string
+		inputs []string
+		want   string
+	}{
+		{"named numeric key", []string{"--csv", "-k", "price:n"}, []string{prices},
+			"name,price\nb,-1\n\"multi\nline\",2.5\n\"Smith, J\",10\n"},
+		{"quoted separator", []string{"--csv", "-k", "1,1"}, []string{prices},
+			"name,price\n\"Smith, J\",10\nb,-1\n\"multi\nline\",2.5\n"},
+		{"header of each input", []string{"--csv", "-k", "price:nr"}, []string{prices, more},
+			"name,price\n\"Smith, J\",10\n\"say \"\"hi\"\"\",3\n\"multi\nline\",2.5\nb,-1\n"},
+		{"merge", []string{"--csv", "-m", "-k", "price:n"}, []string{more, more},
+			"name,price\n\"say \"\"hi\"\"\",3\n\"say \"\"hi\"\"\",3\n"},
+		{"no header", []string{"--csv", "--header=false", "-k", "2n"}, []string{prices},
+			"b,-1\nname,price\n\"multi\nline\",2.5\n\"Smith, J\",10\n"},
+		{"tsv", []string{"--tsv", "-k", "price:n"}, []string{tsv}, "name\tprice\nb\t2\na\t10\n"},
+		// Записи выводятся как во входе: кавычки, пробелы и CRLF не меняются
+		{"original bytes", []string{"--csv", "-k", "price:n"}, []string{crlf},
+			"name,price\r\n b ,1\r\n\"a\",2\r\n\"c\r\nd\",3\r\n"},
+		// В TSV кавычка - обычный символ
+		{"tsv quotes", []string{"--tsv", "--header=false", "-k", "2,2"}, []string{quotes},
+			"b\"c\t\"1\"\n\"x\ty\"\t2\n"},
+	}
+	for _, tt := range tests {
+		for _, size := range []string{"64M", "1b"} {
+			t.Run(tt.name+"/"+size, func(t *testing.T) {
+				tmpDir := t.TempDir()
+				fs, _, err := options.ParseArgs(append([]string{"-S", size, "--batch-size", "2", "-T", tmpDir}, tt.args...))
+				if err != nil {
+					t.Fatal(err)
+				}
+				out := filepath.Join(dir, "out.csv")
+				if err := ExternalSort(tt.inputs, out, *fs); err != nil {
+					t.Fatal(err)
+				}
+				data, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != tt.want {
+					t.Errorf("got\n%s\nwant\n%s", data, tt.want)
+				}
+				assertEmptyDir(t, tmpDir)
+			})
+		}
+	}
+
+	fs, _, _ := options.ParseArgs([]string{"--csv", "-k", "qty"})
+	if err := ExternalSort([]string{prices}, filepath.Join(dir, "out.csv"), *fs); err == nil {
+		t.Error("expected error for unknown column")
+	}
+
+	// Столбцы в другом порядке: ключ price указывал бы на имена второго файла
+	swapped := write("swapped.csv", "price,name\n5,c\n")
+	quoted := write("quoted.csv", "\"name\",price\nc,5\n")
+	for _, mode := range [][]string{nil, {"-m"}} {
+		fs, _, _ = options.ParseArgs(append([]string{"--csv", "-k", "price:n"}, mode...))
+		err := ExternalSort([]string{prices, swapped}, filepath.Join(dir, "out.csv"), *fs)
+		if err == nil || !strings.Contains(err.Error(), "swapped.csv") {
+			t.Errorf("%v: expected header mismatch error, got %v", mode, err)
+		}
+	}
+	// Заголовки сравниваются по полям, кавычки не важны
+	fs, _, _ = options.ParseArgs([]string{"--csv", "-k", "price:n"})
+	if err := ExternalSort([]string{more, quoted}, filepath.Join(dir, "out.csv"), *fs); err != nil {
+		t.Errorf("equal headers with different quoting: %v", err)
+	}
+}
+
+func TestOutputInPlace(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data.txt")
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink("data.txt", link); err != nil {
+		t.Fatal(err)
+	}
+	assertFile := func(want string, mode os.FileMode) {
+		t.Helper()
+		got, err := os.ReadFile(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		info, err := os.Stat(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != mode {
+			t.Errorf("mode = %v, want %v", info.Mode(), mode)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+			t.Errorf("unexpected files in output directory: %v", entries)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		input  string
+		inputs []string
+		output string
+		want   string
+	}{
+		{"sort", []string{"-S", "1b", "--batch-size", "2"}, "d\nb\nc\na\n", []string{data}, data, "a\nb\nc\nd\n"},
+		{"merge", []string{"-m", "-r"}, "d\nc\nb\na\n", []string{data, data}, data, "d\nd\nc\nc\nb\nb\na\na\n"},
+		{"symlink", []string{"-u"}, "d\nb\nb\na\nc\n", []string{link}, link, "a\nb\nc\nd\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(data, []byte(tt.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(data, 0o640); err != nil {
+				t.Fatal(err)
+			}
+			fs, _, err := options.ParseArgs(append([]string{"-T", t.TempDir()}, tt.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ExternalSort(tt.inputs, tt.output, *fs); err != nil {
+				t.Fatal(err)
+			}
+			assertFile(tt.want, 0o640)
+		})
+	}
+
+	// При ошибке прежнее содержимое сохраняется
+	fs, _, _ := options.ParseArgs(nil)
+	if err := ExternalSort([]string{data, filepath.Join(dir, "missing")}, data, *fs); err == nil {
+		t.Fatal("expected error for missing input")
+	}
+	assertFile("a\nb\nc\nd\n", 0o640)
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("temporary file %s was not removed", e.Name())
+	}
+}
+
+func TestMultiPassMerge(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	rnd := rand.New(rand.NewPCG(3, 4))
+	var sb strings.Builder
+	for range 2000 {
+		fmt.Fprintf(&sb, "%d\t%d\n", rnd.IntN(50), rnd.IntN(1000))
+	}
+	if err := os.WriteFile(input, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sortFile := func(args ...string) string {
+		t.Helper()
+		tmpDir := t.TempDir()
+		fs, _, err := options.ParseArgs(append([]string{"-T", tmpDir, "-k", "1,1n"}, args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, "out.txt")
+		if err := ExternalSort([]string{input}, out, *fs); err != nil {
+			t.Fatal(err)
+		}
+		assertEmptyDir(t, tmpDir)
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	want := sortFile()
+	// 64-байтные чанки дают сотни файлов и несколько проходов слияния
+	for _, batch := range []string{"2", "3", "16"} {
+		if got := sortFile("-S", "64b", "--batch-size", batch); got != want {
+			t.Errorf("batch size %s: output differs from single pass", batch)
+		}
+	}
+}
+
+func TestRemoveTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	f, err := createTemp(dir, "test_*.tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	RemoveTempFiles()
+	defer func() {
+		tempFiles.Lock()
+		tempFiles.closed = false
+		tempFiles.Unlock()
+	}()
+
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Errorf("temporary file still exists: %v", err)
+	}
+	if _, err := createTemp(dir, "test_*.tmp"); err != ErrCleanedUp {
+		t.Errorf("createTemp after cleanup: got %v, want ErrCleanedUp", err)
+	}
+}
+
+func TestMergeDoesNotResort(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "unsorted.txt")
+	if err := os.WriteFile(input, []byte("c\na\nb\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs, _, err := options.ParseArgs([]string{"-m", "-T", dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.txt")
+	if err := ExternalSort([]string{input}, out, *fs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "c\na\nb\n" {
+		t.Errorf("merge of a single file changed it: %q", got)
+	}
+}
+
+func TestCollation(t *testing.T) {
+	input := []string{"яблоко", "Юла", "ель", "Ёлка", "Ель", "ёж", "еда", "арбуз", "Арбуз", "zebra", "Apple"}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			// Без локали - побайтовое сравнение: латиница раньше кириллицы, Ё (U+0401) раньше А
+			args: nil,
+			want: []string{"Apple", "zebra", "Ёлка", "Арбуз", "Ель", "Юла", "арбуз", "еда", "ель", "яблоко", "ёж"},
+		},
+		{
+			// ё отличается от е только на втором уровне, строчные идут раньше заглавных
+			args: []string{"--locale", "ru"},
+			want: []string{"Apple", "zebra", "арбуз", "Арбуз", "еда", "ёж", "Ёлка", "ель", "Ель", "Юла", "яблоко"},
+		},
+		{
+			args: []string{"--locale", "ru_RU.UTF-8", "-r"},
+			want: []string{"яблоко", "Юла", "Ель", "ель", "Ёлка", "ёж", "еда", "Арбуз", "арбуз", "zebra", "Apple"},
+		},
+		{
+			// -f без локали, как в локали C, приводит к верхнему регистру только ASCII
+			args: []string{"-f"},
+			want: []string{"Apple", "zebra", "Ёлка", "Арбуз", "Ель", "Юла", "арбуз", "еда", "ель", "яблоко", "ёж"},
+		},
+	}
+	for _, tt := range tests {
+		fs, _, err := options.ParseArgs(tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ss := MakeSortStruct(slices.Clone(input), NewComparator(ConfigFromFlags(*fs)))
+		ss.StringsSort()
+		if !slices.Equal(ss.lines, tt.want) {
+			t.Errorf("%v:\ngot  %q\nwant %q", tt.args, ss.lines, tt.want)
+		}
+	}
+}
+
+func TestCheckSorted(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		args    []string
+		line    int // 0 - отсортировано
+		text    string
+	}{
+		{"sorted", "a\nb\nc\n", []string{"-c"}, 0, ""},
+		{"empty", "", []string{"-c"}, 0, ""},
+		{"disorder", "a\nc\nb\nd\n", []string{"-c"}, 3, "b"},
+		{"quiet", "b\na\n", []string{"-C"}, 2, "a"},
+		{"numeric", "2\n10\n9\n", []string{"-c", "-n"}, 3, "9"},
+		{"reverse", "3\n2\n2\n5\n", []string{"-c", "-n", "-r"}, 4, "5"},
+		{"duplicates allowed", "a\na\n", []string{"-c"}, 0, ""},
+		{"unique strict", "a 1\na 2\n", []string{"-c", "-u", "-k", "1,1"}, 2, "a 2"},
+		{"stable ignores last resort", "a 2\na 1\n", []string{"-c", "-s", "-k", "1,1"}, 0, ""},
+		{"last resort", "a 2\na 1\n", []string{"-c", "-k", "1,1"}, 2, "a 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := filepath.Join(t.TempDir(), "input.txt")
+			if err := os.WriteFile(input, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			fs, _, err := options.ParseArgs(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ExternalSort([]string{input}, filepath.Join(t.TempDir(), "out.txt"), *fs)
+			if tt.line == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var disorder *DisorderError
+			if !errors.As(err, &disorder) {
+				t.Fatalf("expected DisorderError, got %v", err)
+			}
+			if disorder.Line != tt.line || disorder.Text != tt.text {
+				t.Errorf("got line %d %q, want %d %q", disorder.Line, disorder.Text, tt.line, tt.text)
+			}
+			want := fmt.Sprintf("%s:%d: disorder: %s", input, tt.line, tt.text)
+			if err.Error() != want {
+				t.Errorf("message %q, want %q", err.Error(), want)
+			}
+		})
+	}
+
+	fs, _, _ := options.ParseArgs([]string{"-c"})
+	if err := ExternalSort([]string{"a", "b"}, filepath.Join(t.TempDir(), "out.txt"), *fs); err == nil {
+		t.Error("expected error for -c with several files")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	// Пары упорядочены так, как их выводит GNU sort -V (coreutils 9.1)
+	tests := [][2]string{
+		{"v1.9", "v1.10"},
+		{"v1.9~rc1", "v1.9"},
+		{"v1.9", "v1.9-rc1"},
+		{"1.2.3", "1.2.3.tar.gz"},
+		{"1.2.3.zip", "1.2.10.tar.gz"},
+		{"", "."},
+		{".", ".."},
+		{"..", ".hidden"},
+		{".hidden", "..foo"},
+		{".a1", ".1"},
+		{"a", "a."},
+		{"file~", "file"},
+		{"9", "10"},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt[0], tt[1]); got >= 0 {
+			t.Errorf("compareVersions(%q, %q) = %d, want < 0", tt[0], tt[1], got)
+		}
+		if got := compareVersions(tt[1], tt[0]); got <= 0 {
+			t.Errorf("compareVersions(%q, %q) = %d, want > 0", tt[1], tt[0], got)
+		}
+	}
+}
+
+func TestParseGeneral(t *testing.T) {
+	tests := []struct {
+		text string
+		kind int
+		want float64
+	}{
+		{"1e-3", generalNumber, 0.001},
+		{"  -2.5E+2x", generalNumber, -250},
+		{"0x10", generalNumber, 16},
+		{"0x1p4", generalNumber, 16},
+		{"0x", generalNumber, 0},
+		{"1e", generalNumber, 1},
+		{".5", generalNumber, 0.5},
+		{"abc", generalInvalid, 0},
+		{"", generalInvalid, 0},
+		{"NaN", generalNaN, 0},
+		{"-nan", generalNaN, 0},
+	}
+	for _, tt := range tests {
+		flt, _, kind := parseGeneral(tt.text)
+		if kind != tt.kind {
+			t.Errorf("parseGeneral(%q) kind = %d, want %d", tt.text, kind, tt.kind)
+			continue
+		}
+		if kind == generalNumber {
+			if got, _ := flt.Float64(); got != tt.want {
+				t.Errorf("parseGeneral(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		}
+	}
+
+	inf, _, _ := parseGeneral("-Infinity")
+	if !inf.IsInf() || inf.Sign() > 0 {
+		t.Errorf("parseGeneral(-Infinity) = %v", inf)
+	}
+	huge, _, _ := parseGeneral("1e500")
+	if huge.IsInf() {
+		t.Error("1e500 should stay finite, as with long double")
+	}
+}
@@ -1,100 +1,30 @@
 package sortpkg
 
 import (
-	"bufio"
-	"fmt"
-	"io"
+	"context"
 	"os"
-	"strings"
 
 	"github.com/pozedorum/WB_project_2/task10/pkg/options"
 )
 
+// ProcessStdio сортирует stdin и выводит результат в stdout
 func ProcessStdio(fs options.FlagStruct) error {
-	// Создаем временный файл для входных данных
-	tmpInput, err := os.CreateTemp("", "sort_input_*.tmp")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpInput.Name())
-	defer tmpInput.Close()
-
-	// Копируем stdin во временный файл
-	if _, err = io.Copy(tmpInput, os.Stdin); err != nil {
-		return err
-	}
-	tmpInput.Close() // Закрываем, чтобы убедиться в записи
-
-	// Создаем временный файл для результатов
-	tmpOutput, err := os.CreateTemp("", "sort_output_*.tmp")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpOutput.Name())
-	defer tmpOutput.Close()
-
-	// Выполняем сортировку
-	if err = ExternalSort(tmpInput.Name(), tmpOutput.Name(), fs); err != nil {
-		return err
-	}
-
-	// Копируем результат в stdout
-	if _, err = tmpOutput.Seek(0, 0); err != nil {
-		return err
-	} // Перематываем на начало
-
-	_, err = io.Copy(os.Stdout, tmpOutput)
-	return err
+	return ExternalSortToStdout([]string{"-"}, fs)
 }
 
-func ExternalSortToStdout(inputFile string, fs options.FlagStruct) error {
-	// Создаем временный файл для результатов
-	tmpOutput, err := os.CreateTemp("", "sort_output_*.tmp")
-	if err != nil {
-		return err
-	}
-	defer os.Remove(tmpOutput.Name())
-	defer tmpOutput.Close()
-
-	// Выполняем сортировку
-	if err = ExternalSort(inputFile, tmpOutput.Name(), fs); err != nil {
-		return err
-	}
-
-	// Копируем результат в stdout
-	if _, err = tmpOutput.Seek(0, 0); err != nil {
-		return err
+// ExternalSortToStdout сортирует (или сливает с -m) входные файлы и выводит результат в stdout
+func ExternalSortToStdout(inputFiles []string, fs options.FlagStruct) error {
+	inputs := make([]Input, len(inputFiles))
+	for i, name := range inputFiles {
+		inputs[i] = FileInput(name)
 	}
-
-	_, err = io.Copy(os.Stdout, tmpOutput)
-	return err
+	return Sort(context.Background(), ConfigFromFlags(fs), inputs, os.Stdout)
 }
 
-func ProcessInteractiveInput(fs options.FlagStruct) {
-	reader := bufio.NewReader(os.Stdin)
-	var lines []string
-
+// ProcessInteractiveInput читает строки с терминала до Ctrl+D и выводит их отсортированными
+func ProcessInteractiveInput(fs options.FlagStruct) error {
 	// Выводим подсказку
 	os.Stderr.WriteString("Enter text to sort (Ctrl+D to finish):\n")
 
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			break // EOF или другая ошибка
-		}
-		lines = append(lines, strings.TrimSuffix(line, "\n"))
-	}
-
-	// Сортируем и выводим
-	ss := MakeSortStruct(lines, fs)
-	ss.StringsSort()
-
-	writer := bufio.NewWriter(os.Stdout)
-	defer writer.Flush()
-
-	for _, line := range ss.lines {
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			fmt.Printf("ExternalSortStruct.sortAndSaveChunk - file.WriteString: %v", err)
-		}
-	}
+	return ProcessStdio(fs)
 }
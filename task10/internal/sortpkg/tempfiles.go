@@ -0,0 +1,77 @@
+package sortpkg
+
+import (
+	"errors"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// ErrCleanedUp возвращается при попытке создать временный файл после RemoveTempFiles
+var ErrCleanedUp = errors.New("temporary files are already cleaned up")
+
+// tempFiles - все временные файлы процесса, чтобы удалить их и при прерывании
+var tempFiles = struct {
+	sync.Mutex
+	names  map[string]struct{}
+	closed bool
+}{names: make(map[string]struct{})}
+
+// createTemp создаёт временный файл в dir (пустая строка - системный каталог) и регистрирует его
+func createTemp(dir, pattern string) (*os.File, error) {
+	return registerTemp(func() (*os.File, error) { return os.CreateTemp(dir, pattern) })
+}
+
+// createTempPerm как createTemp, но создаёт файл prefix+число с правами perm (с учётом umask), а не 0600
+func createTempPerm(dir, prefix string, perm os.FileMode) (*os.File, error) {
+	return registerTemp(func() (*os.File, error) {
+		for range 10000 {
+			name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10))
+			f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+			if !errors.Is(err, fs.ErrExist) {
+				return f, err
+			}
+		}
+		return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"), Err: fs.ErrExist}
+	})
+}
+
+// registerTemp создаёт файл под блокировкой, чтобы RemoveTempFiles не пропустил его
+func registerTemp(create func() (*os.File, error)) (*os.File, error) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	if tempFiles.closed {
+		return nil, ErrCleanedUp
+	}
+	f, err := create()
+	if err != nil {
+		return nil, err
+	}
+	tempFiles.names[f.Name()] = struct{}{}
+	return f, nil
+}
+
+// removeTemp удаляет временный файл и снимает его с учёта
+func removeTemp(name string) {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	if _, ok := tempFiles.names[name]; ok {
+		os.Remove(name)
+		delete(tempFiles.names, name)
+	}
+}
+
+// RemoveTempFiles удаляет все временные файлы и запрещает создавать новые.
+// Вызывается из обработчика SIGINT перед выходом
+func RemoveTempFiles() {
+	tempFiles.Lock()
+	defer tempFiles.Unlock()
+	for name := range tempFiles.names {
+		os.Remove(name)
+	}
+	clear(tempFiles.names)
+	tempFiles.closed = true
+}
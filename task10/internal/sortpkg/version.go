@@ -0,0 +1,115 @@
+package sortpkg
+
+// compareVersions сравнивает строки как filevercmp из gnulib, который использует GNU sort -V:
+// числа внутри строк сравниваются по значению, '~' идёт раньше всего, даже конца строки,
+// а суффиксы вида ".tar.gz" учитываются только при равенстве остальной части
+func compareVersions(a, b string) int {
+	switch {
+	case a == "" || b == "":
+		return compareInts(len(a), len(b))
+	case a[0] == '.' && b[0] != '.':
+		return -1
+	case a[0] != '.' && b[0] == '.':
+		return 1
+	case a[0] == '.':
+		// "." идёт первой, затем "..", затем остальные имена с точкой в начале
+		for _, special := range []string{".", ".."} {
+			if a == special || b == special {
+				return compareBools(a != special, b != special)
+			}
+		}
+	}
+
+	aPrefix, bPrefix := filePrefixLen(a), filePrefixLen(b)
+	res := verrevcmp(a[:aPrefix], b[:bPrefix])
+	if res != 0 || (aPrefix == len(a) && bPrefix == len(b)) {
+		return res
+	}
+	return verrevcmp(a, b)
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// filePrefixLen возвращает длину строки без самого длинного суффикса вида (\.[A-Za-z~][A-Za-z0-9~]*)*$.
+// Суффикс может начинаться с первого символа, как в coreutils 9.1: ".a1" целиком считается суффиксом
+func filePrefixLen(s string) int {
+	for i := 0; ; i++ {
+		prefixLen := i
+		for i+1 < len(s) && s[i] == '.' && (isAlpha(s[i+1]) || s[i+1] == '~') {
+			for i += 2; i < len(s) && (isAlpha(s[i]) || isDigit(s[i]) || s[i] == '~'); i++ {
+			}
+		}
+		if i >= len(s) {
+			return prefixLen
+		}
+	}
+}
+
+func isAlpha(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// versionOrder - вес символа вне числа: конец строки раньше букв, буквы раньше прочих символов, '~' раньше всего
+func versionOrder(s string, pos int) int {
+	if pos == len(s) {
+		return -1
+	}
+	ch := s[pos]
+	switch {
+	case isDigit(ch):
+		return 0
+	case isAlpha(ch):
+		return int(ch)
+	case ch == '~':
+		return -2
+	default:
+		return int(ch) + 256
+	}
+}
+
+// verrevcmp сравнивает чередующиеся нечисловые и числовые части, как dpkg
+func verrevcmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			ac, bc := versionOrder(a, i), versionOrder(b, j)
+			if ac != bc {
+				return compareInts(ac, bc)
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && j < len(b) && isDigit(a[i]) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = compareInts(int(a[i]), int(b[j]))
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
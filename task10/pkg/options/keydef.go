@@ -0,0 +1,193 @@
+package options
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeyDef описывает ключ сортировки в синтаксисе GNU sort: F[.C][OPTS][,F[.C][OPTS]]
+type KeyDef struct {
+	StartField int // номер поля начала ключа, с 1
+	StartChar  int // позиция символа в поле начала, с 1
+	EndField   int // номер поля конца ключа, 0 - до конца строки
+	EndChar    int // позиция последнего символа в поле конца, 0 - до конца поля
+	// Name - имя столбца из заголовка CSV/TSV; номера полей проставляются после чтения заголовка
+	Name string
+
+	SkipStartBlanks bool // b в начале ключа
+	SkipEndBlanks   bool // b в конце ключа
+	Numeric         bool
+	Human           bool
+	Month           bool
+	Reverse         bool
+	Fold            bool // f - без учёта регистра
+	Dictionary      bool // d - только пробелы, буквы и цифры
+	General         bool // g - числа с плавающей точкой, inf и nan
+	Version         bool // V - номера версий
+	Random          bool // R - равные ключи рядом, группы в случайном порядке
+}
+
+// HasOptions сообщает, заданы ли у ключа собственные модификаторы.
+// Ключ без модификаторов наследует глобальные флаги
+func (k KeyDef) HasOptions() bool {
+	return k.SkipStartBlanks || k.SkipEndBlanks || k.Numeric || k.Human || k.Month || k.Reverse ||
+		k.Fold || k.Dictionary || k.General || k.Version || k.Random
+}
+
+// ParseKeyDef разбирает значение флага -k.
+// Ключ, начинающийся не с цифры, задаёт столбец CSV/TSV по имени: NAME[:OPTS]
+func ParseKeyDef(spec string) (KeyDef, error) {
+	key := KeyDef{StartChar: 1}
+	if spec != "" && !isDigit(spec[0]) {
+		return parseNamedKey(spec)
+	}
+	start, end, hasEnd := strings.Cut(spec, ",")
+
+	field, char, opts, err := parseKeyPos(start)
+	if err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	if field == 0 {
+		return key, fmt.Errorf("invalid key %q: field number is zero", spec)
+	}
+	if char == 0 {
+		return key, fmt.Errorf("invalid key %q: character offset is zero", spec)
+	}
+	key.StartField = field
+	if char > 0 {
+		key.StartChar = char
+	}
+	if err := key.applyOptions(opts, true); err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+
+	if hasEnd {
+		field, char, opts, err = parseKeyPos(end)
+		if err != nil {
+			return key, fmt.Errorf("invalid key %q: %w", spec, err)
+		}
+		if field == 0 {
+			return key, fmt.Errorf("invalid key %q: field number is zero", spec)
+		}
+		key.EndField = field
+		key.EndChar = max(char, 0)
+		if err := key.applyOptions(opts, false); err != nil {
+			return key, fmt.Errorf("invalid key %q: %w", spec, err)
+		}
+	}
+
+	if err := key.CheckModes(); err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	return key, nil
+}
+
+// parseNamedKey разбирает ключ по имени столбца; опции отделяются последним ':'
+func parseNamedKey(spec string) (KeyDef, error) {
+	key := KeyDef{StartChar: 1, Name: spec}
+	opts := ""
+	if i := strings.LastIndexByte(spec, ':'); i >= 0 {
+		key.Name, opts = spec[:i], spec[i+1:]
+	}
+	if key.Name == "" {
+		return key, fmt.Errorf("invalid key %q: empty column name", spec)
+	}
+	// Ключ по имени - один столбец целиком, b относится к обоим концам
+	if err := key.applyOptions(opts, true); err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	key.SkipEndBlanks = key.SkipStartBlanks
+	if err := key.CheckModes(); err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	return key, nil
+}
+
+// parseKeyPos разбирает F[.C][OPTS]; char равен -1, если позиция символа не указана
+func parseKeyPos(pos string) (field, char int, opts string, err error) {
+	digits := leadingDigits(pos)
+	if digits == "" {
+		return 0, 0, "", fmt.Errorf("missing field number")
+	}
+	if field, err = strconv.Atoi(digits); err != nil {
+		return 0, 0, "", fmt.Errorf("invalid field number %q", digits)
+	}
+	pos = pos[len(digits):]
+
+	char = -1
+	if strings.HasPrefix(pos, ".") {
+		digits = leadingDigits(pos[1:])
+		if digits == "" {
+			return 0, 0, "", fmt.Errorf("missing character offset")
+		}
+		if char, err = strconv.Atoi(digits); err != nil {
+			return 0, 0, "", fmt.Errorf("invalid character offset %q", digits)
+		}
+		pos = pos[1+len(digits):]
+	}
+	return field, char, pos, nil
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+func (k *KeyDef) applyOptions(opts string, start bool) error {
+	for _, opt := range opts {
+		switch opt {
+		case 'b':
+			if start {
+				k.SkipStartBlanks = true
+			} else {
+				k.SkipEndBlanks = true
+			}
+		case 'n':
+			k.Numeric = true
+		case 'h':
+			k.Human = true
+		case 'M':
+			k.Month = true
+		case 'r':
+			k.Reverse = true
+		case 'f':
+			k.Fold = true
+		case 'd':
+			k.Dictionary = true
+		case 'g':
+			k.General = true
+		case 'V':
+			k.Version = true
+		case 'R':
+			k.Random = true
+		default:
+			return fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return nil
+}
+
+// CheckModes проверяет, что выбран не более чем один режим сравнения
+func (k KeyDef) CheckModes() error {
+	modes := ""
+	for _, mode := range []struct {
+		set    bool
+		letter string
+	}{{k.General, "g"}, {k.Human, "h"}, {k.Month, "M"}, {k.Numeric, "n"}, {k.Random, "R"}, {k.Version, "V"}} {
+		if mode.set {
+			modes += mode.letter
+		}
+	}
+	if len(modes) > 1 {
+		return fmt.Errorf("options '-%s' are incompatible", modes)
+	}
+	return nil
+}
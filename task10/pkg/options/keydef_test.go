@@ -0,0 +1,43 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestParseKeyDef(t *testing.T) {
+	tests := []struct {
+		spec string
+		want KeyDef
+	}{
+		{"2", KeyDef{StartField: 2, StartChar: 1}},
+		{"2,2n", KeyDef{StartField: 2, StartChar: 1, EndField: 2, Numeric: true}},
+		{"1,1r", KeyDef{StartField: 1, StartChar: 1, EndField: 1, Reverse: true}},
+		{"3.2,3.5", KeyDef{StartField: 3, StartChar: 2, EndField: 3, EndChar: 5}},
+		{"3.2b,3.5b", KeyDef{StartField: 3, StartChar: 2, EndField: 3, EndChar: 5, SkipStartBlanks: true, SkipEndBlanks: true}},
+		{"2.1,4.0", KeyDef{StartField: 2, StartChar: 1, EndField: 4}},
+		{"4hr", KeyDef{StartField: 4, StartChar: 1, Human: true, Reverse: true}},
+		{"1,1M", KeyDef{StartField: 1, StartChar: 1, EndField: 1, Month: true}},
+		{"2Rf", KeyDef{StartField: 2, StartChar: 1, Random: true, Fold: true}},
+		{"price:nr", KeyDef{StartChar: 1, Name: "price", Numeric: true, Reverse: true}},
+		{"a:b:b", KeyDef{StartChar: 1, Name: "a:b", SkipStartBlanks: true, SkipEndBlanks: true}},
+		{"name", KeyDef{StartChar: 1, Name: "name"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeyDef(tt.spec)
+		if err != nil {
+			t.Errorf("ParseKeyDef(%q): %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeyDef(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyDefErrors(t *testing.T) {
+	for _, spec := range []string{"", "0", "1.0", ":n", "price:x", "a:nM", "1,0", "1.", "2x", "1,2nM", "1n,2h", "1,1nR"} {
+		if _, err := ParseKeyDef(spec); err == nil {
+			t.Errorf("ParseKeyDef(%q): expected error", spec)
+		}
+	}
+}
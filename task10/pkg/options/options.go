@@ -1,55 +1,335 @@
 package options
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	flag "github.com/spf13/pflag"
+	"golang.org/x/text/language"
 )
 
+// DefaultBufferSize - размер чанка по умолчанию, если не задан -S
+const DefaultBufferSize = 64 << 20
+
+// DefaultBatchSize - число файлов, сливаемых за один проход, если не задан --batch-size
+const DefaultBatchSize = 16
+
+// maxParallel ограничивает число воркеров по умолчанию, как в GNU sort
+const maxParallel = 8
+
 type FlagStruct struct {
-	KFlag *int
-	NFlag *bool
-	RFlag *bool
-	UFlag *bool
-	MFlag *bool
-	BFlag *bool
-	CFlag *bool
-	HFlag *bool
+	KFlag      *[]string
+	TFlag      *string
+	NFlag      *bool
+	RFlag      *bool
+	UFlag      *bool
+	MFlag      *bool
+	BFlag      *bool
+	CFlag      *bool
+	HFlag      *bool
+	FFlag      *bool
+	DFlag      *bool
+	GFlag      *bool
+	VFlag      *bool
+	RandomFlag *bool
+	SFlag      *string
+
+	MergeFlag  *bool
+	CQuietFlag *bool // -C: как -c, но без сообщения
+	StableFlag *bool
+
+	ParallelFlag     *int
+	BatchSizeFlag    *int
+	TempDirFlag      *string
+	LocaleFlag       *string
+	RandomSourceFlag *string
+	CSVFlag          *bool
+	TSVFlag          *bool
+	HeaderFlag       *bool
+	OFlag            *string
+	ZFlag            *bool
+
+	BufferSize int64    // разобранное значение -S в байтах
+	Parallel   int      // число воркеров, не меньше 1
+	Keys       []KeyDef // разобранные значения -k
+	Locale     string   // тег BCP 47 для сортировки строк, пустая строка - побайтовое сравнение
+	Separator  string   // разделитель полей из -t, пустая строка - переход от пробелов к непробельным символам
+	RandomSeed []byte   // ключ хеширования для -R из --random-source, nil - случайный
 }
 
+// ParseOptions разбирает os.Args и завершает программу при ошибке.
+// Без --locale локаль берётся из LC_ALL, LC_COLLATE или LANG
 func ParseOptions() (*FlagStruct, []string) {
+	fs, args, err := ParseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sort: %v\n", err)
+		os.Exit(2)
+	}
+	if *fs.LocaleFlag == "" {
+		// Неизвестная локаль в окружении означает побайтовое сравнение, как в GNU sort
+		fs.Locale, _ = ParseLocale(localeFromEnv())
+	}
+	return fs, args
+}
+
+func localeFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_COLLATE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// ParseLocale переводит имя локали POSIX (ru_RU.UTF-8) или тег BCP 47 (ru) в тег для сортировки.
+// Для локалей C и POSIX возвращает пустую строку
+func ParseLocale(name string) (string, error) {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	if name == "" || name == "C" || name == "POSIX" {
+		return "", nil
+	}
+	tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %q", name)
+	}
+	return tag.String(), nil
+}
+
+// ParseArgs разбирает аргументы командной строки без обращения к глобальному состоянию
+func ParseArgs(arguments []string) (*FlagStruct, []string, error) {
 	var fs FlagStruct
+	flags := flag.NewFlagSet("sort", flag.ContinueOnError)
 
-	fs.KFlag = flag.IntP("k", "k", 1, "sort by column number N")
-	fs.NFlag = flag.BoolP("n", "n", false, "try to interpret strings as numbers and sort by it")
-	fs.RFlag = flag.BoolP("r", "r", false, "sort in reverse order")
-	fs.UFlag = flag.BoolP("u", "u", false, "output only sorted unique string")
-	fs.MFlag = flag.BoolP("M", "M", false, "sort by month")
-	fs.BFlag = flag.BoolP("b", "b", false, "ignore trailing blanks")
-	fs.CFlag = flag.BoolP("c", "c", false, "check if data is sorted")
-	fs.HFlag = flag.BoolP("h", "h", false, "sort by numerical value, taking into account suffixes")
+	fs.KFlag = flags.StringArrayP("k", "k", nil, "sort via a key KEYDEF=F[.C][OPTS][,F[.C][OPTS]], may be repeated")
+	fs.TFlag = flags.StringP("t", "t", "", "use SEP instead of non-blank to blank transition as field separator")
+	fs.NFlag = flags.BoolP("n", "n", false, "try to interpret strings as numbers and sort by it")
+	fs.RFlag = flags.BoolP("r", "r", false, "sort in reverse order")
+	fs.UFlag = flags.BoolP("u", "u", false, "output only sorted unique string")
+	fs.MFlag = flags.BoolP("M", "M", false, "sort by month")
+	fs.BFlag = flags.BoolP("b", "b", false, "ignore leading blanks")
+	fs.CFlag = flags.BoolP("c", "c", false, "check for sorted input and report the first disorder; do not sort")
+	fs.CQuietFlag = flags.BoolP("C", "C", false, "like -c, but do not report the first disorder")
+	fs.StableFlag = flags.BoolP("stable", "s", false, "stabilize sort by disabling last-resort comparison")
+	fs.HFlag = flags.BoolP("h", "h", false, "sort by numerical value, taking into account suffixes")
+	fs.MergeFlag = flags.BoolP("merge", "m", false, "merge already sorted files; do not sort")
+	fs.FFlag = flags.BoolP("f", "f", false, "fold lower case to upper case characters")
+	fs.DFlag = flags.BoolP("d", "d", false, "consider only blanks and alphanumeric characters")
+	fs.GFlag = flags.BoolP("g", "g", false, "compare according to general numerical value (floats, inf, nan)")
+	fs.VFlag = flags.BoolP("V", "V", false, "natural sort of (version) numbers within text")
+	fs.RandomFlag = flags.BoolP("random-sort", "R", false, "shuffle, but group identical keys")
+	fs.RandomSourceFlag = flags.String("random-source", "", "get random bytes from FILE")
+	fs.OFlag = flags.StringP("output", "o", "", "write result to FILE instead of standard output; FILE may also be an input")
+	fs.ZFlag = flags.BoolP("zero-terminated", "z", false, "line delimiter is NUL, not newline")
+	fs.CSVFlag = flags.Bool("csv", false, "input is CSV: records may contain quoted separators and newlines")
+	fs.TSVFlag = flags.Bool("tsv", false, "input is tab-separated values, quotes are ordinary characters")
+	fs.HeaderFlag = flags.Bool("header", true, "with --csv/--tsv, the first record is a header kept on top; keys may name its columns (-k price:n)")
+	fs.SFlag = flags.StringP("buffer-size", "S", "", "use SIZE bytes for each sorted chunk (suffixes b, K, M, G, T; default unit K)")
+	fs.ParallelFlag = flags.Int("parallel", 0, "sort chunks with N workers (default: number of CPUs, at most 8)")
+	fs.BatchSizeFlag = flags.Int("batch-size", DefaultBatchSize, "merge at most N temporary files at once")
+	fs.LocaleFlag = flags.String("locale", "", "collate strings by the rules of LOCALE, e.g. ru (default: $LC_ALL, $LC_COLLATE or $LANG)")
+	fs.TempDirFlag = flags.StringP("temporary-directory", "T", "", "use DIR for temporary files (default: $TMPDIR or /tmp)")
 
 	// Переопределяем Usage для отображения только коротких флагов
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options] input_file\n", os.Args[0])
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [file...]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(arguments); err != nil {
+		return nil, nil, err
+	}
+	if err := fs.validate(); err != nil {
+		return nil, nil, err
+	}
+	return &fs, flags.Args(), nil
+}
+
+// validate проверяет несовместимые режимы сравнения и разбирает ключи и разделитель
+func (fs *FlagStruct) validate() error {
+	global := KeyDef{General: *fs.GFlag, Human: *fs.HFlag, Month: *fs.MFlag, Numeric: *fs.NFlag, Version: *fs.VFlag, Random: *fs.RandomFlag}
+	if err := global.CheckModes(); err != nil {
+		return err
+	}
+	random := global.Random
+	for _, spec := range *fs.KFlag {
+		key, err := ParseKeyDef(spec)
+		if err != nil {
+			return err
+		}
+		random = random || key.Random
+		fs.Keys = append(fs.Keys, key)
+	}
+	// Как в GNU sort, источник читается, только если он нужен
+	if random && *fs.RandomSourceFlag != "" {
+		seed, err := ReadRandomSource(*fs.RandomSourceFlag)
+		if err != nil {
+			return err
+		}
+		fs.RandomSeed = seed
 	}
+	if err := fs.validateCSV(); err != nil {
+		return err
+	}
+	if *fs.OFlag != "" && (*fs.CFlag || *fs.CQuietFlag) {
+		check := "c"
+		if *fs.CQuietFlag {
+			check = "C"
+		}
+		return fmt.Errorf("options '-%so' are incompatible", check)
+	}
+	switch {
+	case *fs.CSVFlag:
+		fs.Separator = ","
+	case *fs.TSVFlag:
+		fs.Separator = "\t"
+	case *fs.TFlag == `\0`:
+		fs.Separator = "\x00"
+	case len(*fs.TFlag) > 1:
+		return fmt.Errorf("multi-character tab %q", *fs.TFlag)
+	default:
+		fs.Separator = *fs.TFlag
+	}
+
+	locale, err := ParseLocale(*fs.LocaleFlag)
+	if err != nil {
+		return err
+	}
+	fs.Locale = locale
+
+	fs.BufferSize = DefaultBufferSize
+	if *fs.SFlag != "" {
+		size, err := ParseSize(*fs.SFlag)
+		if err != nil {
+			return err
+		}
+		fs.BufferSize = size
+	}
+
+	if *fs.BatchSizeFlag < 2 {
+		return fmt.Errorf("invalid --batch-size argument %d: minimum is 2", *fs.BatchSizeFlag)
+	}
+
+	switch {
+	case *fs.ParallelFlag < 0:
+		return fmt.Errorf("invalid number of threads: %d", *fs.ParallelFlag)
+	case *fs.ParallelFlag == 0:
+		fs.Parallel = DefaultParallel()
+	default:
+		fs.Parallel = *fs.ParallelFlag
+	}
+	return nil
+}
+
+// validateCSV проверяет сочетание --csv/--tsv с -t и ключами по именам столбцов
+func (fs *FlagStruct) validateCSV() error {
+	csv := *fs.CSVFlag || *fs.TSVFlag
+	switch {
+	case *fs.CSVFlag && *fs.TSVFlag:
+		return errors.New("options '--csv' and '--tsv' are incompatible")
+	case csv && *fs.TFlag != "":
+		return errors.New("option '-t' is incompatible with '--csv' and '--tsv'")
+	case csv && *fs.ZFlag:
+		return errors.New("option '-z' is incompatible with '--csv' and '--tsv'")
+	}
+	for _, key := range fs.Keys {
+		if key.Name != "" && (!csv || !*fs.HeaderFlag) {
+			return fmt.Errorf("key %q names a column: requires --csv or --tsv with a header", key.Name)
+		}
+	}
+	return nil
+}
+
+// RandomSeedSize - сколько байт читается из --random-source
+const RandomSeedSize = 16
 
-	// Парсим аргументы
-	flag.Parse()
+// ReadRandomSource читает ключ хеширования для -R из файла
+func ReadRandomSource(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open failed: %w", err)
+	}
+	defer f.Close()
+	seed := make([]byte, RandomSeedSize)
+	if _, err := io.ReadFull(f, seed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("'%s': end of file", name)
+		}
+		return nil, err
+	}
+	return seed, nil
+}
 
-	return &fs, flag.Args()
+// DefaultParallel - число воркеров, если не задан --parallel
+func DefaultParallel() int {
+	return min(runtime.NumCPU(), maxParallel)
+}
+
+// ParseSize разбирает размер буфера как в GNU sort -S: число с необязательным
+// суффиксом b (байты), K, M, G или T. Число без суффикса задаётся в килобайтах
+func ParseSize(size string) (int64, error) {
+	digits := leadingDigits(size)
+	num, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid buffer size %q", size)
+	}
+
+	shift := 10
+	switch suffix := strings.ToUpper(size[len(digits):]); suffix {
+	case "B":
+		shift = 0
+	case "", "K":
+	case "M":
+		shift = 20
+	case "G":
+		shift = 30
+	case "T":
+		shift = 40
+	default:
+		return 0, fmt.Errorf("invalid buffer size %q", size)
+	}
+	if num <= 0 || num > (1<<62)>>shift {
+		return 0, fmt.Errorf("invalid buffer size %q", size)
+	}
+	return num << shift, nil
 }
 
 func (fs *FlagStruct) PrintFlags() {
 	fmt.Println("flag k -", *(fs.KFlag))
+	fmt.Println("flag t -", *(fs.TFlag))
 	fmt.Println("flag n -", *(fs.NFlag))
 	fmt.Println("flag r -", *(fs.RFlag))
 	fmt.Println("flag u -", *(fs.UFlag))
-	fmt.Println("flag m -", *(fs.MFlag))
+	fmt.Println("flag M -", *(fs.MFlag))
 	fmt.Println("flag b -", *(fs.BFlag))
 	fmt.Println("flag c -", *(fs.CFlag))
+	fmt.Println("flag C -", *(fs.CQuietFlag))
+	fmt.Println("flag s -", *(fs.StableFlag))
 	fmt.Println("flag h -", *(fs.HFlag))
+	fmt.Println("flag f -", *(fs.FFlag))
+	fmt.Println("flag d -", *(fs.DFlag))
+	fmt.Println("flag g -", *(fs.GFlag))
+	fmt.Println("flag V -", *(fs.VFlag))
+	fmt.Println("flag R -", *(fs.RandomFlag))
+	fmt.Println("flag random-source -", *(fs.RandomSourceFlag))
+	fmt.Println("flag csv -", *(fs.CSVFlag))
+	fmt.Println("flag tsv -", *(fs.TSVFlag))
+	fmt.Println("flag header -", *(fs.HeaderFlag))
+	fmt.Println("flag o -", *(fs.OFlag))
+	fmt.Println("flag z -", *(fs.ZFlag))
+	fmt.Println("flag locale -", *(fs.LocaleFlag))
+	fmt.Println("flag m (merge) -", *(fs.MergeFlag))
+	fmt.Println("flag S -", *(fs.SFlag))
+	fmt.Println("flag parallel -", *(fs.ParallelFlag))
+	fmt.Println("flag batch-size -", *(fs.BatchSizeFlag))
+	fmt.Println("flag T -", *(fs.TempDirFlag))
 }
@@ -0,0 +1,130 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseArgsKeysAndSeparator(t *testing.T) {
+	fs, args, err := ParseArgs([]string{"-t", ":", "-k", "2,2n", "-k", "1,1r", "file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.Keys) != 2 || !fs.Keys[0].Numeric || !fs.Keys[1].Reverse {
+		t.Errorf("unexpected keys %+v", fs.Keys)
+	}
+	if fs.Separator != ":" {
+		t.Errorf("separator = %q, want %q", fs.Separator, ":")
+	}
+	if len(args) != 1 || args[0] != "file" {
+		t.Errorf("args = %v", args)
+	}
+
+	if _, _, err := ParseArgs([]string{"-t", "::"}); err == nil {
+		t.Error("expected error for multi-character tab")
+	}
+}
+
+func TestParseArgsCSV(t *testing.T) {
+	fs, _, err := ParseArgs([]string{"--tsv", "-k", "price:n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.Separator != "\t" || fs.Keys[0].Name != "price" {
+		t.Errorf("separator = %q, keys = %+v", fs.Separator, fs.Keys)
+	}
+	for _, args := range [][]string{
+		{"-k", "price:n"},
+		{"--csv", "--header=false", "-k", "price"},
+		{"--csv", "-t", ";"},
+		{"--csv", "--tsv"},
+	} {
+		if _, _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestRandomSource(t *testing.T) {
+	dir := t.TempDir()
+	long := filepath.Join(dir, "long")
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(long, []byte(strings.Repeat("seed", 8)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(short, []byte("seed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, _, err := ParseArgs([]string{"-R", "--random-source", long})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fs.RandomSeed) != "seedseedseedseed" {
+		t.Errorf("seed = %q", fs.RandomSeed)
+	}
+	if _, _, err := ParseArgs([]string{"-k", "1R", "--random-source", short}); err == nil {
+		t.Error("expected error for short random source")
+	}
+	// Без -R источник не читается
+	if _, _, err := ParseArgs([]string{"--random-source", filepath.Join(dir, "missing")}); err != nil {
+		t.Errorf("unused random source: %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+	}{
+		{"512M", 512 << 20},
+		{"10", 10 << 10},
+		{"100b", 100},
+		{"2k", 2 << 10},
+		{"1G", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.size)
+		if err != nil {
+			t.Errorf("ParseSize(%q): %v", tt.size, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+	for _, size := range []string{"", "M", "0", "12X", "-5K", "99999999999T"} {
+		if _, err := ParseSize(size); err == nil {
+			t.Errorf("ParseSize(%q): expected error", size)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"", ""},
+		{"C", ""},
+		{"POSIX", ""},
+		{"C.UTF-8", ""},
+		{"ru", "ru"},
+		{"ru_RU.UTF-8", "ru-RU"},
+		{"de_DE@euro", "de-DE"},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocale(tt.name)
+		if err != nil {
+			t.Errorf("ParseLocale(%q): %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLocale(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if _, err := ParseLocale("not a locale"); err == nil {
+		t.Error("expected error for invalid locale")
+	}
+}
@@ -0,0 +1,187 @@
+// Package sort exposes the external sorter of task10 as a library.
+package sort
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pozedorum/WB_project_2/task10/internal/sortpkg"
+	"github.com/pozedorum/WB_project_2/task10/pkg/options"
+)
+
+// Key is a sort key, see ParseKey for the -k syntax
+type Key = options.KeyDef
+
+// ParseKey parses a key definition in the GNU -k syntax, e.g. "2,2n" or "3.2,3.5r"
+func ParseKey(spec string) (Key, error) {
+	return options.ParseKeyDef(spec)
+}
+
+// Progress reports the state of a running sort
+type Progress = sortpkg.Progress
+
+// Stage is the current stage of a sort
+type Stage = sortpkg.Stage
+
+const (
+	StageSort  = sortpkg.StageSort
+	StageMerge = sortpkg.StageMerge
+	StageDone  = sortpkg.StageDone
+)
+
+// DisorderError is returned in Check mode for the first line out of order
+type DisorderError = sortpkg.DisorderError
+
+// ErrCheckInputs is returned when Check mode is given other than one input
+var ErrCheckInputs = errors.New("check mode needs exactly one input")
+
+// Options configures a Sorter. The zero value sorts whole lines by bytes
+type Options struct {
+	Keys      []Key  // пустой список - ключом служит вся строка
+	Separator string // один символ, пустая строка - переход от пробелов к непробельным символам
+
+	// CSV включает разбор записей через encoding/csv, Separator по умолчанию ',', "\t" - TSV.
+	// Первая запись каждого входа - заголовок, ключи могут ссылаться на его столбцы по имени
+	CSV      bool
+	NoHeader bool // у CSV нет заголовка
+
+	ZeroTerminated bool // записи завершаются нулевым байтом, а не переводом строки
+
+	// Глобальные режимы, ключи без своих модификаторов наследуют их
+	Numeric             bool
+	Human               bool
+	Month               bool
+	General             bool
+	Version             bool
+	Random              bool // равные ключи рядом, группы в случайном порядке
+	Reverse             bool
+	IgnoreLeadingBlanks bool
+	FoldCase            bool
+	Dictionary          bool
+	Locale              string // ru, ru_RU.UTF-8 и т.п., пустая строка или C - побайтовое сравнение
+	RandomSeed          []byte // ключ хеширования для Random, nil - новый для каждого вызова Sort
+
+	Unique bool
+	Stable bool
+	Merge  bool // входы уже отсортированы, только слить
+	Check  bool // только проверить порядок, Sort возвращает *DisorderError
+
+	BufferSize int64  // размер чанка в байтах, 0 - options.DefaultBufferSize
+	Parallel   int    // число воркеров, 0 - по числу CPU, не больше 8
+	BatchSize  int    // файлов за один проход слияния, 0 - options.DefaultBatchSize
+	TempDir    string // пустая строка - os.TempDir()
+
+	// Progress is called after each sorted chunk and merge pass, never concurrently within one Sort call
+	Progress func(Progress)
+}
+
+// Sorter sorts lines of text like GNU sort, spilling chunks to temporary files
+type Sorter struct {
+	cfg sortpkg.Config
+}
+
+// New validates opts and returns a Sorter. A Sorter may be used concurrently
+func New(opts Options) (*Sorter, error) {
+	global := options.KeyDef{
+		StartField:      1,
+		StartChar:       1,
+		SkipStartBlanks: opts.IgnoreLeadingBlanks,
+		SkipEndBlanks:   opts.IgnoreLeadingBlanks,
+		Numeric:         opts.Numeric,
+		Human:           opts.Human,
+		Month:           opts.Month,
+		Reverse:         opts.Reverse,
+		Fold:            opts.FoldCase,
+		Dictionary:      opts.Dictionary,
+		General:         opts.General,
+		Version:         opts.Version,
+		Random:          opts.Random,
+	}
+	if err := global.CheckModes(); err != nil {
+		return nil, err
+	}
+	for _, key := range opts.Keys {
+		if (key.Name == "" && key.StartField < 1) || key.StartChar < 1 {
+			return nil, fmt.Errorf("invalid key start %d.%d", key.StartField, key.StartChar)
+		}
+		if err := key.CheckModes(); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Separator) > 1 {
+		return nil, fmt.Errorf("multi-character tab %q", opts.Separator)
+	}
+	if opts.CSV && opts.Separator == "" {
+		opts.Separator = ","
+	}
+	if opts.CSV && opts.ZeroTerminated {
+		return nil, errors.New("CSV records cannot be zero-terminated")
+	}
+	for _, key := range opts.Keys {
+		if key.Name != "" && (!opts.CSV || opts.NoHeader) {
+			return nil, fmt.Errorf("key %q names a column: requires CSV with a header", key.Name)
+		}
+	}
+	locale, err := options.ParseLocale(opts.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sortpkg.Config{
+		Global:         global,
+		Keys:           opts.Keys,
+		Separator:      opts.Separator,
+		Locale:         locale,
+		RandomSeed:     opts.RandomSeed,
+		Unique:         opts.Unique,
+		Stable:         opts.Stable,
+		Merge:          opts.Merge,
+		Check:          opts.Check,
+		CSV:            opts.CSV,
+		Header:         !opts.NoHeader,
+		ZeroTerminated: opts.ZeroTerminated,
+		BufferSize:     opts.BufferSize,
+		Parallel:       opts.Parallel,
+		BatchSize:      opts.BatchSize,
+		TempDir:        opts.TempDir,
+		Progress:       opts.Progress,
+	}
+	switch {
+	case cfg.BufferSize < 0:
+		return nil, fmt.Errorf("invalid buffer size %d", cfg.BufferSize)
+	case cfg.BufferSize == 0:
+		cfg.BufferSize = options.DefaultBufferSize
+	}
+	switch {
+	case cfg.Parallel < 0:
+		return nil, fmt.Errorf("invalid number of threads: %d", cfg.Parallel)
+	case cfg.Parallel == 0:
+		cfg.Parallel = options.DefaultParallel()
+	}
+	switch {
+	case cfg.BatchSize == 0:
+		cfg.BatchSize = options.DefaultBatchSize
+	case cfg.BatchSize < 2:
+		return nil, fmt.Errorf("invalid batch size %d: minimum is 2", cfg.BatchSize)
+	}
+	return &Sorter{cfg: cfg}, nil
+}
+
+// Sort reads all inputs as one dataset and writes the sorted lines to w.
+// In Merge mode the inputs must already be sorted, in Check mode nothing is written.
+// When ctx is cancelled Sort stops and returns ctx.Err(); temporary files are always removed.
+func (s *Sorter) Sort(ctx context.Context, inputs []io.Reader, w io.Writer) error {
+	if s.cfg.Check && len(inputs) != 1 {
+		return ErrCheckInputs
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	in := make([]sortpkg.Input, len(inputs))
+	for i, r := range inputs {
+		in[i] = sortpkg.ReaderInput("-", r)
+	}
+	return sortpkg.Sort(ctx, s.cfg, in, w)
+}
@@ -0,0 +1,132 @@
+package sort
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSorterSort(t *testing.T) {
+	key, err := ParseKey("2,2n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := ParseKey("price:n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		opts   Options
+		inputs []string
+		want   string
+	}{
+		{"bytes", Options{}, []string{"b\na\nc\n"}, "a\nb\nc\n"},
+		{"several inputs", Options{}, []string{"c\na\n", "b\n"}, "a\nb\nc\n"},
+		{"numeric key", Options{Keys: []Key{key}, Separator: ","}, []string{"x,10\ny,-1\nz,2\n"}, "y,-1\nz,2\nx,10\n"},
+		{"reverse unique", Options{Reverse: true, Unique: true}, []string{"a\nb\na\n"}, "b\na\n"},
+		{"merge", Options{Merge: true, Numeric: true}, []string{"1\n3\n", "2\n10\n"}, "1\n2\n3\n10\n"},
+		{"csv named key", Options{CSV: true, Keys: []Key{price}}, []string{"item,price\n\"a, b\",3\nc,1\n"}, "item,price\nc,1\n\"a, b\",3\n"},
+		{"zero terminated", Options{ZeroTerminated: true}, []string{"b\nx\x00a\x00c"}, "a\x00b\nx\x00c\x00"},
+		{"tiny chunks", Options{BufferSize: 4, BatchSize: 2, Parallel: 2}, []string{"e\nd\nc\nb\na\n"}, "a\nb\nc\nd\ne\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.TempDir = t.TempDir()
+			s, err := New(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			readers := make([]io.Reader, len(tt.inputs))
+			for i, in := range tt.inputs {
+				readers[i] = strings.NewReader(in)
+			}
+			var out bytes.Buffer
+			if err := s.Sort(context.Background(), readers, &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+			if left, _ := os.ReadDir(opts.TempDir); len(left) != 0 {
+				t.Errorf("temporary files left: %v", left)
+			}
+		})
+	}
+}
+
+func TestSorterCheck(t *testing.T) {
+	s, err := New(Options{Check: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Sort(context.Background(), []io.Reader{strings.NewReader("a\nc\nb\n")}, io.Discard)
+	var disorder *DisorderError
+	if !errors.As(err, &disorder) || disorder.Line != 3 {
+		t.Fatalf("got %v, want disorder at line 3", err)
+	}
+	if err := s.Sort(context.Background(), nil, io.Discard); !errors.Is(err, ErrCheckInputs) {
+		t.Fatalf("got %v, want ErrCheckInputs", err)
+	}
+}
+
+func TestSorterCancel(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	var stages []Stage
+	s, err := New(Options{BufferSize: 64, TempDir: dir, Progress: func(p Progress) {
+		stages = append(stages, p.Stage)
+		if p.ChunksSorted > 0 {
+			cancel()
+		}
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := strings.Repeat("line\n", 100000)
+	err = s.Sort(ctx, []io.Reader{strings.NewReader(input)}, io.Discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if len(stages) == 0 {
+		t.Error("progress callback was not called")
+	}
+	if left, _ := os.ReadDir(dir); len(left) != 0 {
+		t.Errorf("temporary files left: %v", left)
+	}
+}
+
+func TestSorterProgress(t *testing.T) {
+	var last Progress
+	s, err := New(Options{BufferSize: 2, BatchSize: 2, TempDir: t.TempDir(), Progress: func(p Progress) { last = p }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sort(context.Background(), []io.Reader{strings.NewReader("d\nc\nb\na\n")}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if last.Stage != StageDone || last.LinesRead != 4 || last.LinesWritten != 4 || last.Chunks != last.ChunksSorted || last.MergePass < 2 {
+		t.Errorf("unexpected final progress %+v", last)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Numeric: true, Month: true},
+		{Separator: "ab"},
+		{BatchSize: 1},
+		{Parallel: -1},
+		{CSV: true, ZeroTerminated: true},
+		{Keys: []Key{{}}},
+		{Keys: []Key{{StartChar: 1, Name: "price"}}},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) returned no error", opts)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/pozedorum/WB_project_2/task11/pkg/anagram"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// indexConfig - флаги работы с AnagramIndex
+type indexConfig struct {
+	save   string
+	load   string
+	query  string
+	sub    string
+	listen string
+}
+
+func (ic indexConfig) enabled() bool {
+	return ic.save != "" || ic.load != "" || ic.query != "" || ic.sub != "" || ic.listen != ""
+}
+
+// run reads the dictionaries and writes the sets of anagrams or answers index queries,
+// returns the exit status: 0 - success, 2 - usage or read error
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("anagrams", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", string(anagram.FormatText), "output format: text, json or csv")
+	normalization := fs.String("norm", "none", "Unicode normalization: none, nfc or nfkd")
+	opts := anagram.DefaultOptions
+	fs.BoolVar(&opts.FoldCase, "fold-case", opts.FoldCase, "compare and print words in lower case")
+	fs.BoolVar(&opts.IgnoreSpacePunct, "ignore-punct", false, "ignore spaces and punctuation, for multi-word anagrams")
+	fs.BoolVar(&opts.IgnoreMarks, "ignore-marks", false, "ignore diacritical marks")
+	fs.BoolVar(&opts.YoAsYe, "yo", false, "treat ё as е")
+	var ic indexConfig
+	fs.StringVar(&ic.save, "save-index", "", "build an index from the dictionaries and save it to `FILE`")
+	fs.StringVar(&ic.load, "load-index", "", "load an index from `FILE` instead of reading dictionaries, matcher options are taken from the file")
+	fs.StringVar(&ic.query, "query", "", "print anagrams of `WORD` found in the dictionary")
+	fs.StringVar(&ic.sub, "sub", "", "print words formable from the letters of `WORD`, longest first")
+	fs.StringVar(&ic.listen, "serve", "", "serve the index over HTTP JSON API on `ADDR`, e.g. :8080")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: anagrams [options] [file ...]\nOne word per line, without files or with \"-\" words are read from stdin.\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	outFormat, err := anagram.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if opts.Normalization, err = anagram.ParseNormalization(*normalization); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	files := fs.Args()
+	if ic.load != "" {
+		if err := checkLoadArgs(fs, ic); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			fs.Usage()
+			return 2
+		}
+	}
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	var idx *anagram.AnagramIndex
+	if ic.load != "" {
+		if idx, err = loadIndex(ic.load); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+		return runIndex(idx, ic, stdout, stderr)
+	}
+
+	g := anagram.NewGrouperWithMatcher(anagram.NewMatcher(opts))
+	for _, name := range files {
+		if err := addFile(g, name, stdin); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+	if ic.enabled() {
+		return runIndex(anagram.NewIndex(g), ic, stdout, stderr)
+	}
+
+	out := bufio.NewWriter(stdout)
+	if err := anagram.WriteGroups(out, g.Groups(), outFormat); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// checkLoadArgs отклоняет флаги, которые с -load-index молча игнорировались бы:
+// словари и опции Matcher берутся из файла индекса, а без запроса загрузка бесполезна
+func checkLoadArgs(fs *flag.FlagSet, ic indexConfig) error {
+	if fs.NArg() > 0 {
+		return errors.New("-load-index does not read dictionary files")
+	}
+	var matcherFlag string
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "fold-case", "ignore-punct", "ignore-marks", "yo", "norm":
+			matcherFlag = f.Name
+		}
+	})
+	if matcherFlag != "" {
+		return fmt.Errorf("-%s cannot be used with -load-index, matcher options are stored in the index", matcherFlag)
+	}
+	if ic.query == "" && ic.sub == "" && ic.listen == "" && ic.save == "" {
+		return errors.New("-load-index requires one of -query, -sub, -serve or -save-index")
+	}
+	return nil
+}
+
+// runIndex сохраняет индекс, отвечает на -query и -sub по слову на строку и запускает HTTP-сервер
+func runIndex(idx *anagram.AnagramIndex, ic indexConfig, stdout, stderr io.Writer) int {
+	if ic.save != "" {
+		if err := saveIndex(idx, ic.save); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 2
+		}
+	}
+
+	out := bufio.NewWriter(stdout)
+	if ic.query != "" {
+		for _, word := range idx.Anagrams(ic.query) {
+			fmt.Fprintln(out, word)
+		}
+	}
+	if ic.sub != "" {
+		for _, word := range idx.SubAnagrams(ic.sub) {
+			fmt.Fprintln(out, word)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+
+	if ic.listen != "" {
+		fmt.Fprintf(stderr, "serving %d words on %s\n", idx.Len(), ic.listen)
+		err := http.ListenAndServe(ic.listen, idx.Handler())
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+func saveIndex(idx *anagram.AnagramIndex, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = idx.Save(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func loadIndex(name string) (*anagram.AnagramIndex, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := anagram.LoadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return idx, nil
+}
+
+func addFile(g *anagram.Grouper, name string, stdin io.Reader) error {
+	if name == "-" {
+		return g.AddFrom(stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := g.AddFrom(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
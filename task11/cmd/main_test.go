@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	dict := filepath.Join(dir, "dict.txt")
+	if err := os.WriteFile(dict, []byte("листок\nслиток\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	index := filepath.Join(dir, "dict.idx")
+
+	tests := []struct {
+		name   string
+		args   []string
+		input  string
+		output string
+		status int
+	}{
+		{"stdin", nil, "пятак\nпятка\nстол\n", "пятак: пятак пятка\n", 0},
+		{"files and stdin", []string{dict, "-"}, "столик\n", "листок: листок слиток столик\n", 0},
+		{"csv", []string{"-format", "csv"}, "ab\nba\n", "key,word\nab,ab\nab,ba\n", 0},
+		{"matcher options", []string{"-ignore-punct", "-yo"}, "Dormitory\ndirty room\nёлка\nкела\n", "dormitory: dirty room dormitory\nёлка: кела ёлка\n", 0},
+		{"case sensitive", []string{"-fold-case=false"}, "Ab\nba\nbA\n", "Ab: Ab bA\n", 0},
+		{"unknown normalization", []string{"-norm", "nfd"}, "", "", 2},
+		{"save index and query", []string{"-save-index", index, "-query", "СЛИТОК", "-yo"}, "листок\nслиток\nлист\nёлка\n", "листок\nслиток\n", 0},
+		{"load index", []string{"-load-index", index, "-sub", "колист", "-query", "кела"}, "", "ёлка\nлисток\nслиток\nлист\n", 0},
+		{"bad index", []string{"-load-index", dict, "-query", "листок"}, "", "", 2},
+		{"load index with files", []string{"-load-index", index, "-query", "кела", dict}, "", "", 2},
+		{"load index with matcher options", []string{"-load-index", index, "-query", "кела", "-fold-case=false"}, "", "", 2},
+		{"load index without action", []string{"-load-index", index}, "", "", 2},
+		{"unknown format", []string{"-format", "xml"}, "", "", 2},
+		{"missing file", []string{filepath.Join(dir, "missing")}, "", "", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.args, strings.NewReader(test.input), &stdout, &stderr)
+			if status != test.status {
+				t.Errorf("wrong status\nexpected: %d\nactual: %d\nstderr: %s", test.status, status, stderr.String())
+			}
+			if stdout.String() != test.output {
+				t.Errorf("wrong output\nexpected: %q\nactual: %q", test.output, stdout.String())
+			}
+		})
+	}
+}
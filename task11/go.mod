@@ -1,3 +1,5 @@
 module github.com/pozedorum/WB_project_2/task11
 
 go 1.24.4
+
+require golang.org/x/text v0.28.0
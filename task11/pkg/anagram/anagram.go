@@ -0,0 +1,110 @@
+// Package anagram groups words of a dictionary into sets of anagrams.
+package anagram
+
+import (
+	"bufio"
+	"hash/maphash"
+	"io"
+	"slices"
+	"strings"
+)
+
+// maxWordSize - максимальная длина строки словаря
+const maxWordSize = 1 << 20
+
+// Group is a set of anagrams
+type Group struct {
+	Key   string   `json:"key"`   // первое встреченное слово множества
+	Words []string `json:"words"` // все слова множества по возрастанию, без повторов
+}
+
+// Grouper collects words and groups them by the signature of its Matcher, the sorted letters of a word.
+// Groups are indexed by a hash of the signature and the signature itself is not stored:
+// on a hash match it is recomputed from the first word of the group, so every word is kept once.
+type Grouper struct {
+	matcher *Matcher
+	seed    maphash.Seed
+	index   map[uint64]int32 // хеш сигнатуры -> последняя группа с этим хешем
+	next    []int32          // предыдущая группа с тем же хешем, -1 - нет
+	groups  [][]string       // слова групп в порядке появления, первое - ключ
+}
+
+// NewGrouper returns an empty Grouper with DefaultOptions
+func NewGrouper() *Grouper {
+	return NewGrouperWithMatcher(NewMatcher(DefaultOptions))
+}
+
+// NewGrouperWithMatcher returns an empty Grouper that compares words with m
+func NewGrouperWithMatcher(m *Matcher) *Grouper {
+	return &Grouper{matcher: m, seed: maphash.MakeSeed(), index: make(map[uint64]int32)}
+}
+
+// Add adds a word normalized by the Matcher. Repeated words and words
+// without letters to compare (empty signature) are ignored
+func (g *Grouper) Add(word string) {
+	word = g.matcher.Normalize(word)
+	sig := g.matcher.Signature(word)
+	if sig == "" {
+		return
+	}
+	hash := maphash.String(g.seed, sig)
+
+	head, ok := g.index[hash]
+	for i := head; ok && i >= 0; i = g.next[i] {
+		words := g.groups[i]
+		if g.matcher.Signature(words[0]) != sig {
+			continue // коллизия хеша
+		}
+		if !slices.Contains(words, word) {
+			g.groups[i] = append(words, word)
+		}
+		return
+	}
+
+	if !ok {
+		head = -1
+	}
+	g.index[hash] = int32(len(g.groups))
+	g.next = append(g.next, head)
+	g.groups = append(g.groups, []string{word})
+}
+
+// AddFrom adds every line of r as a word, surrounding spaces are trimmed.
+// The input is read as a stream and is not kept in memory
+func (g *Grouper) AddFrom(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxWordSize)
+	for scanner.Scan() {
+		g.Add(strings.TrimSpace(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// Groups returns the sets of two or more words ordered by their first occurrence,
+// words inside a set are sorted
+func (g *Grouper) Groups() []Group {
+	res := make([]Group, 0)
+	for _, words := range g.groups {
+		if len(words) < 2 {
+			continue
+		}
+		sorted := slices.Clone(words)
+		slices.Sort(sorted)
+		res = append(res, Group{Key: words[0], Words: sorted})
+	}
+	return res
+}
+
+// Anagrams returns the sets of anagrams of words keyed by the first word of each set,
+// as described in task.md
+func Anagrams(words []string) map[string][]string {
+	g := NewGrouper()
+	for _, word := range words {
+		g.Add(word)
+	}
+	res := make(map[string][]string)
+	for _, group := range g.Groups() {
+		res[group.Key] = group.Words
+	}
+	return res
+}
@@ -0,0 +1,130 @@
+package anagram
+
+import (
+	"bytes"
+	"hash/maphash"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnagrams(t *testing.T) {
+	got := Anagrams([]string{"пятак", "пятка", "тяпка", "листок", "слиток", "столик", "стол"})
+	want := map[string][]string{
+		"пятак":  {"пятак", "пятка", "тяпка"},
+		"листок": {"листок", "слиток", "столик"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGrouperGroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Group
+	}{
+		{"first occurrence order", "тяпка\nлисток\nпятак\nслиток\n", []Group{
+			{"тяпка", []string{"пятак", "тяпка"}},
+			{"листок", []string{"листок", "слиток"}},
+		}},
+		{"lower case and duplicates", "Пятак\n  тяпка \nПЯТАК\nпятак\n", []Group{
+			{"пятак", []string{"пятак", "тяпка"}},
+		}},
+		{"no anagrams", "стол\n\nстул\n", []Group{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGrouper()
+			if err := g.AddFrom(strings.NewReader(tt.input)); err != nil {
+				t.Fatal(err)
+			}
+			if got := g.Groups(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrouperHashCollision(t *testing.T) {
+	g := NewGrouper()
+	g.Add("ab")
+	// Подменяем индекс так, будто сигнатура "cd" дала тот же хеш, что и "ab"
+	g.index[maphash.String(g.seed, "cd")] = 0
+	for _, word := range []string{"cd", "ba", "dc"} {
+		g.Add(word)
+	}
+	want := []Group{{"ab", []string{"ab", "ba"}}, {"cd", []string{"cd", "dc"}}}
+	if got := g.Groups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name string
+		opts MatcherOptions
+		a, b string
+		want bool
+	}{
+		{"case sensitive", MatcherOptions{}, "Пятак", "пятка", false},
+		{"fold case", MatcherOptions{FoldCase: true}, "Пятак", "ТЯПКА", true},
+		{"yo differs", MatcherOptions{FoldCase: true}, "ёлка", "елка", false},
+		{"yo as ye", MatcherOptions{FoldCase: true, YoAsYe: true}, "Ёлка", "кела", true},
+		{"decomposed yo as ye", MatcherOptions{YoAsYe: true}, "е\u0308лка", "кела", true},
+		{"composed and decomposed", MatcherOptions{}, "caf\u00e9", "face\u0301", false},
+		{"nfc", MatcherOptions{Normalization: NormNFC}, "caf\u00e9", "\u00e9fac", true},
+		{"nfkd", MatcherOptions{Normalization: NormNFKD}, "\ufb01t", "tif", true},
+		{"marks", MatcherOptions{IgnoreMarks: true}, "caf\u00e9", "face", true},
+		{"spaces and punctuation", MatcherOptions{IgnoreSpacePunct: true}, "dormitory", "dirty room!", true},
+		{"spaces count", MatcherOptions{}, "dormitory", "dirty room", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewMatcher(tt.opts).Match(tt.a, tt.b); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrouperWithMatcher(t *testing.T) {
+	m := NewMatcher(MatcherOptions{FoldCase: true, IgnoreSpacePunct: true, YoAsYe: true})
+	g := NewGrouperWithMatcher(m)
+	for _, word := range []string{"Dormitory", "dirty room", "...", "Ёлка", "кела", "dirty-room"} {
+		g.Add(word)
+	}
+	want := []Group{
+		{"dormitory", []string{"dirty room", "dirty-room", "dormitory"}},
+		{"ёлка", []string{"кела", "ёлка"}},
+	}
+	if got := g.Groups(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteGroups(t *testing.T) {
+	groups := []Group{{"ab", []string{"ab", "ba"}}, {"c,d", []string{"c,d", "d,c"}}}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatText, "ab: ab ba\nc,d: c,d d,c\n"},
+		{FormatCSV, "key,word\nab,ab\nab,ba\n\"c,d\",\"c,d\"\n\"c,d\",\"d,c\"\n"},
+		{FormatJSON, "[\n  {\n    \"key\": \"ab\",\n    \"words\": [\n      \"ab\",\n      \"ba\"\n    ]\n  },\n" +
+			"  {\n    \"key\": \"c,d\",\n    \"words\": [\n      \"c,d\",\n      \"d,c\"\n    ]\n  }\n]\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteGroups(&buf, groups, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,57 @@
+package anagram
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is the output format of WriteGroups
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// ParseFormat checks that s is one of the supported formats
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected text, json or csv", s)
+}
+
+// WriteGroups writes groups to w in the given format:
+// text - "key: word word" per group, json - an array of groups,
+// csv - a "key,word" row per word with a header
+func WriteGroups(w io.Writer, groups []Group, format Format) error {
+	switch format {
+	case FormatText, "":
+		for _, g := range groups {
+			if _, err := fmt.Fprintf(w, "%s: %s\n", g.Key, strings.Join(g.Words, " ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"key", "word"})
+		for _, g := range groups {
+			for _, word := range g.Words {
+				cw.Write([]string{g.Key, word})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
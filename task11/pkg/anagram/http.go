@@ -0,0 +1,70 @@
+package anagram
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler returns an http.Handler with the following endpoints:
+//   - GET /anagrams?word=X - words of the dictionary with the same letters as X
+//   - GET /subanagrams?word=X&limit=N - words formable from the letters of X, at most N if limit is set
+//   - GET /stats - number of words and sets of anagrams in the index
+func (idx *AnagramIndex) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /anagrams", idx.serveAnagrams)
+	mux.HandleFunc("GET /subanagrams", idx.serveSubAnagrams)
+	mux.HandleFunc("GET /stats", idx.serveStats)
+	return mux
+}
+
+type queryResponse struct {
+	Word  string   `json:"word"`
+	Words []string `json:"words"`
+}
+
+func (idx *AnagramIndex) serveAnagrams(w http.ResponseWriter, r *http.Request) {
+	word := r.URL.Query().Get("word")
+	if word == "" {
+		http.Error(w, "missing word parameter", http.StatusBadRequest)
+		return
+	}
+	words := idx.Anagrams(word)
+	if words == nil {
+		words = []string{}
+	}
+	writeJSON(w, queryResponse{word, words})
+}
+
+func (idx *AnagramIndex) serveSubAnagrams(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	word := query.Get("word")
+	if word == "" {
+		http.Error(w, "missing word parameter", http.StatusBadRequest)
+		return
+	}
+	words := idx.SubAnagrams(word)
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		words = words[:min(limit, len(words))]
+	}
+	writeJSON(w, queryResponse{word, words})
+}
+
+func (idx *AnagramIndex) serveStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		Words  int `json:"words"`
+		Groups int `json:"groups"`
+	}{idx.Len(), len(idx.groups)})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,217 @@
+package anagram
+
+import (
+	"cmp"
+	"io"
+	"slices"
+	"unicode/utf8"
+)
+
+// AnagramIndex answers queries over a dictionary built once: anagrams of a word
+// in O(len(word)) and sub-anagrams, words formable from its letters, with a letter-count trie.
+// An index is read-only after construction and safe for concurrent use
+type AnagramIndex struct {
+	matcher  *Matcher
+	groups   []Group          // все множества, в том числе из одного слова
+	hashes   []uint64         // хеш мультимножества букв каждого множества
+	next     []int32          // предыдущее множество с тем же хешем, -1 - нет
+	index    map[uint64]int32 // хеш -> последнее множество с этим хешем
+	alphabet map[rune]int32   // буква -> её номер в дереве
+	nodes    []trieNode       // nodes[0] - корень, пустой набор букв
+}
+
+// trieNode - набор букв: путь от корня задаёт, сколько раз встречается каждая буква.
+// Переходы идут по возрастанию номера буквы, поэтому у каждого набора ровно один узел
+type trieNode struct {
+	group    int32 // множество слов с этим набором букв, -1 - нет
+	children []trieEdge
+}
+
+// trieEdge - переход по букве letter, встречающейся count раз
+type trieEdge struct {
+	letter int32
+	count  int32
+	node   int32
+}
+
+// NewIndex builds an index over all words collected by g, including words without anagrams
+func NewIndex(g *Grouper) *AnagramIndex {
+	idx := &AnagramIndex{
+		matcher:  g.matcher,
+		groups:   make([]Group, 0, len(g.groups)),
+		index:    make(map[uint64]int32),
+		alphabet: make(map[rune]int32),
+		nodes:    []trieNode{{group: -1}},
+	}
+
+	// Редкие буквы ближе к корню: меньше ветвей, которые приходится обходить при поиске
+	freq := make(map[rune]int)
+	for _, words := range g.groups {
+		for r := range letterCounts(g.matcher.letters(words[0])) {
+			freq[r]++
+		}
+	}
+	letters := make([]rune, 0, len(freq))
+	for r := range freq {
+		letters = append(letters, r)
+	}
+	slices.SortFunc(letters, func(a, b rune) int {
+		return cmp.Or(cmp.Compare(freq[a], freq[b]), cmp.Compare(a, b))
+	})
+	for i, r := range letters {
+		idx.alphabet[r] = int32(i)
+	}
+
+	for _, words := range g.groups {
+		sorted := slices.Clone(words)
+		slices.Sort(sorted)
+		letters := g.matcher.letters(words[0])
+		idx.addGroup(Group{Key: words[0], Words: sorted}, letterHash(letters))
+		idx.insert(letters, int32(len(idx.groups)-1))
+	}
+	return idx
+}
+
+// BuildIndex reads a dictionary, one word per line, and builds an index over it
+func BuildIndex(r io.Reader, opts MatcherOptions) (*AnagramIndex, error) {
+	g := NewGrouperWithMatcher(NewMatcher(opts))
+	if err := g.AddFrom(r); err != nil {
+		return nil, err
+	}
+	return NewIndex(g), nil
+}
+
+// addGroup добавляет множество в хеш-индекс
+func (idx *AnagramIndex) addGroup(group Group, hash uint64) {
+	head, ok := idx.index[hash]
+	if !ok {
+		head = -1
+	}
+	idx.index[hash] = int32(len(idx.groups))
+	idx.next = append(idx.next, head)
+	idx.hashes = append(idx.hashes, hash)
+	idx.groups = append(idx.groups, group)
+}
+
+// insert проходит по дереву набором букв, создавая недостающие узлы, и отмечает в конце множество
+func (idx *AnagramIndex) insert(letters []rune, group int32) {
+	path := make([]trieEdge, 0, len(letters))
+	for r, count := range letterCounts(letters) {
+		path = append(path, trieEdge{letter: idx.alphabet[r], count: count})
+	}
+	slices.SortFunc(path, func(a, b trieEdge) int { return cmp.Compare(a.letter, b.letter) })
+
+	node := int32(0)
+	for _, step := range path {
+		children := idx.nodes[node].children
+		i, found := slices.BinarySearchFunc(children, step, compareEdges)
+		if !found {
+			step.node = int32(len(idx.nodes))
+			idx.nodes = append(idx.nodes, trieNode{group: -1})
+			idx.nodes[node].children = slices.Insert(children, i, step)
+		}
+		node = idx.nodes[node].children[i].node
+	}
+	idx.nodes[node].group = group
+}
+
+func compareEdges(a, b trieEdge) int {
+	return cmp.Or(cmp.Compare(a.letter, b.letter), cmp.Compare(a.count, b.count))
+}
+
+// Len returns the number of distinct words in the index
+func (idx *AnagramIndex) Len() int {
+	n := 0
+	for _, g := range idx.groups {
+		n += len(g.Words)
+	}
+	return n
+}
+
+// Anagrams returns the words of the dictionary with the same letters as word, sorted.
+// The word itself is included if it is in the dictionary
+func (idx *AnagramIndex) Anagrams(word string) []string {
+	letters := idx.matcher.letters(idx.matcher.Normalize(word))
+	if len(letters) == 0 {
+		return nil
+	}
+	head, ok := idx.index[letterHash(letters)]
+	for i := head; ok && i >= 0; i = idx.next[i] {
+		// Хеши могут совпасть и у разных наборов букв
+		if sameLetters(letters, idx.matcher.letters(idx.groups[i].Key)) {
+			return slices.Clone(idx.groups[i].Words)
+		}
+	}
+	return nil
+}
+
+// SubAnagrams returns the words of the dictionary that can be made from the letters of word,
+// each letter used at most as many times as it occurs in word.
+// Longer words go first, words of equal length are sorted
+func (idx *AnagramIndex) SubAnagrams(word string) []string {
+	available := make(map[int32]int32)
+	for _, r := range idx.matcher.letters(idx.matcher.Normalize(word)) {
+		if letter, ok := idx.alphabet[r]; ok {
+			available[letter]++
+		}
+	}
+
+	res := make([]string, 0)
+	stack := []int32{0}
+	for len(stack) > 0 {
+		node := &idx.nodes[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		if node.group >= 0 {
+			res = append(res, idx.groups[node.group].Words...)
+		}
+		for _, e := range node.children {
+			if available[e.letter] >= e.count {
+				stack = append(stack, e.node)
+			}
+		}
+	}
+
+	slices.SortFunc(res, func(a, b string) int {
+		return cmp.Or(cmp.Compare(utf8.RuneCountInString(b), utf8.RuneCountInString(a)), cmp.Compare(a, b))
+	})
+	return res
+}
+
+func letterCounts(letters []rune) map[rune]int32 {
+	counts := make(map[rune]int32, len(letters))
+	for _, r := range letters {
+		counts[r]++
+	}
+	return counts
+}
+
+func sameLetters(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := letterCounts(a)
+	for _, r := range b {
+		if counts[r]--; counts[r] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// letterHash - хеш мультимножества букв. Сумма хешей букв не зависит от их порядка,
+// поэтому считается за O(len) без сортировки
+func letterHash(letters []rune) uint64 {
+	var sum uint64
+	for _, r := range letters {
+		sum += mix64(uint64(r))
+	}
+	return mix64(sum ^ uint64(len(letters)))
+}
+
+// mix64 - финализатор splitmix64
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
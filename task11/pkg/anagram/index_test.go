@@ -0,0 +1,186 @@
+package anagram
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testDictionary = "пятак\nпятка\nтяпка\nпят\nтяпка\nкап\nпак\nак\nлисток\nслиток\nстолик\nстол\nЁлка\n"
+
+func newTestIndex(t *testing.T, opts MatcherOptions) *AnagramIndex {
+	t.Helper()
+	idx, err := BuildIndex(strings.NewReader(testDictionary), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func checkQueries(t *testing.T, idx *AnagramIndex) {
+	t.Helper()
+	anagrams := []struct {
+		word string
+		want []string
+	}{
+		{"КАТЯП", []string{"пятак", "пятка", "тяпка"}},
+		{"колист", []string{"листок", "слиток", "столик"}},
+		{"кела", []string{"ёлка"}},
+		{"стул", nil},
+		{"", nil},
+	}
+	for _, tt := range anagrams {
+		if got := idx.Anagrams(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Anagrams(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+
+	sub := []struct {
+		word string
+		want []string
+	}{
+		{"пятак", []string{"пятак", "пятка", "тяпка", "кап", "пак", "пят", "ак"}},
+		{"капа", []string{"кап", "пак", "ак"}},
+		{"лксто", []string{"стол"}},
+		{"xyz", []string{}},
+	}
+	for _, tt := range sub {
+		if got := idx.SubAnagrams(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SubAnagrams(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestAnagramIndex(t *testing.T) {
+	idx := newTestIndex(t, MatcherOptions{FoldCase: true, YoAsYe: true})
+	checkQueries(t, idx)
+	if idx.Len() != 12 {
+		t.Errorf("Len() = %d, want 12", idx.Len())
+	}
+}
+
+func TestAnagramIndexHashCollision(t *testing.T) {
+	idx := newTestIndex(t, DefaultOptions)
+	// Множество с чужими буквами, но тем же хешем, не должно мешать поиску
+	idx.addGroup(Group{Key: "xy", Words: []string{"xy"}}, letterHash([]rune("пятак")))
+	if got := idx.Anagrams("пятак"); !reflect.DeepEqual(got, []string{"пятак", "пятка", "тяпка"}) {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestSaveLoadIndex(t *testing.T) {
+	idx := newTestIndex(t, MatcherOptions{FoldCase: true, YoAsYe: true})
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	loaded, err := LoadIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Опции Matcher сохраняются вместе с индексом
+	checkQueries(t, loaded)
+	if !reflect.DeepEqual(loaded.groups, idx.groups) || !reflect.DeepEqual(loaded.nodes, idx.nodes) {
+		t.Error("loaded index differs from saved")
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("NOTANIDX"), data[8:]...),
+		"truncated": data[:len(data)/2],
+	} {
+		if _, err := LoadIndex(bytes.NewReader(bad)); !errors.Is(err, ErrBadIndex) {
+			t.Errorf("%s: got %v, want ErrBadIndex", name, err)
+		}
+	}
+}
+
+func TestLoadCorruptIndex(t *testing.T) {
+	tests := map[string]func(idx *AnagramIndex){
+		"self loop":       func(idx *AnagramIndex) { idx.nodes[0].children[0].node = 0 },
+		"backward edge":   func(idx *AnagramIndex) { idx.nodes[1].children = []trieEdge{{letter: 1, count: 1, node: 0}} },
+		"unknown letter":  func(idx *AnagramIndex) { idx.nodes[0].children[1].letter = int32(len(idx.alphabet)) },
+		"zero count":      func(idx *AnagramIndex) { idx.nodes[0].children[0].count = 0 },
+		"unsorted edges":  func(idx *AnagramIndex) { slices.Reverse(idx.nodes[0].children) },
+		"duplicate edges": func(idx *AnagramIndex) { idx.nodes[0].children[1] = idx.nodes[0].children[0] },
+	}
+	for name, corrupt := range tests {
+		idx, err := BuildIndex(strings.NewReader("кот\nток\nак\n"), MatcherOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(idx.nodes[0].children) < 2 {
+			t.Fatal("test dictionary must give the root several edges")
+		}
+		corrupt(idx)
+		var buf bytes.Buffer
+		if err := idx.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadIndex(&buf); !errors.Is(err, ErrBadIndex) {
+			t.Errorf("%s: got %v, want ErrBadIndex", name, err)
+		}
+	}
+
+	// Повтор буквы в алфавите: вторая буква заменяется первой прямо в данных
+	idx, err := BuildIndex(strings.NewReader("кот\n"), MatcherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	alphabet := len(indexMagic) + 3
+	if data[alphabet] != 3 {
+		t.Fatalf("unexpected alphabet size %d", data[alphabet])
+	}
+	first, n := binary.Uvarint(data[alphabet+1:])
+	binary.PutUvarint(data[alphabet+1+n:], first) // кириллица занимает 2 байта в uvarint
+	if _, err := LoadIndex(bytes.NewReader(data)); !errors.Is(err, ErrBadIndex) {
+		t.Errorf("repeated letter: got %v, want ErrBadIndex", err)
+	}
+}
+
+func TestIndexHandler(t *testing.T) {
+	srv := httptest.NewServer(newTestIndex(t, DefaultOptions).Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   []string
+	}{
+		{"/anagrams?word=%D1%82%D1%8F%D0%BF%D0%BA%D0%B0", http.StatusOK, []string{"пятак", "пятка", "тяпка"}},
+		{"/anagrams?word=qq", http.StatusOK, []string{}},
+		{"/subanagrams?word=%D0%BA%D0%B0%D0%BF&limit=1", http.StatusOK, []string{"кап"}},
+		{"/anagrams", http.StatusBadRequest, nil},
+		{"/subanagrams?word=a&limit=x", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body queryResponse
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && (err != nil || !reflect.DeepEqual(body.Words, tt.want)) {
+			t.Errorf("%s: got %q (%v), want %q", tt.path, body.Words, err, tt.want)
+		}
+	}
+}
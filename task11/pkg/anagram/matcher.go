@@ -0,0 +1,105 @@
+package anagram
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization is the Unicode normalization form applied to words
+type Normalization int
+
+const (
+	NormNone Normalization = iota
+	NormNFC                // составные символы: "е" + U+0301 и "é" совпадают
+	NormNFKD               // совместимая декомпозиция: "ﬁ" совпадает с "fi"
+)
+
+// ParseNormalization parses none, nfc or nfkd
+func ParseNormalization(s string) (Normalization, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return NormNone, nil
+	case "nfc":
+		return NormNFC, nil
+	case "nfkd":
+		return NormNFKD, nil
+	}
+	return NormNone, fmt.Errorf("unknown normalization %q, expected none, nfc or nfkd", s)
+}
+
+// MatcherOptions configures which words are considered anagrams
+type MatcherOptions struct {
+	FoldCase      bool // слова приводятся к нижнему регистру
+	Normalization Normalization
+	// IgnoreSpacePunct не учитывает пробелы и знаки препинания: "dormitory" и "dirty room" - анаграммы
+	IgnoreSpacePunct bool
+	IgnoreMarks      bool // не учитывает диакритические знаки: "é" совпадает с "e"
+	YoAsYe           bool // "ё" считается "е"
+}
+
+// DefaultOptions are the options of NewGrouper: words are compared in lower case, as task.md requires
+var DefaultOptions = MatcherOptions{FoldCase: true}
+
+// Matcher computes word signatures according to MatcherOptions
+type Matcher struct {
+	opts MatcherOptions
+}
+
+// NewMatcher returns a Matcher with the given options
+func NewMatcher(opts MatcherOptions) *Matcher {
+	return &Matcher{opts: opts}
+}
+
+// Normalize returns the word as it is stored and printed:
+// normalized to the chosen form and folded to lower case
+func (m *Matcher) Normalize(word string) string {
+	switch m.opts.Normalization {
+	case NormNFC:
+		word = norm.NFC.String(word)
+	case NormNFKD:
+		word = norm.NFKD.String(word)
+	}
+	if m.opts.FoldCase {
+		word = strings.ToLower(word)
+	}
+	return word
+}
+
+// Signature returns the letters of a normalized word in ascending order.
+// Words with equal signatures are anagrams
+func (m *Matcher) Signature(word string) string {
+	runes := m.letters(word)
+	slices.Sort(runes)
+	return string(runes)
+}
+
+// letters возвращает сравниваемые буквы нормализованного слова в исходном порядке
+func (m *Matcher) letters(word string) []rune {
+	if m.opts.YoAsYe {
+		// Сначала собираем "е" + U+0308 в "ё", если слово было разложено
+		word = strings.NewReplacer("ё", "е", "Ё", "Е").Replace(norm.NFC.String(word))
+	}
+	if m.opts.IgnoreMarks {
+		word = norm.NFD.String(word)
+	}
+	runes := make([]rune, 0, len(word))
+	for _, r := range word {
+		if m.opts.IgnoreMarks && unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if m.opts.IgnoreSpacePunct && (unicode.IsSpace(r) || unicode.IsPunct(r)) {
+			continue
+		}
+		runes = append(runes, r)
+	}
+	return runes
+}
+
+// Match reports whether a and b are anagrams
+func (m *Matcher) Match(a, b string) bool {
+	return m.Signature(m.Normalize(a)) == m.Signature(m.Normalize(b))
+}
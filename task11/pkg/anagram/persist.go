@@ -0,0 +1,258 @@
+package anagram
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Формат файла индекса: магическая строка и версия, опции Matcher, алфавит,
+// множества слов с хешами и узлы дерева. Числа записываются как uvarint, хеши -
+// 8 байт little-endian. При загрузке ничего не пересчитывается: хеш-индекс
+// заполняется сохранёнными хешами, дерево читается как есть
+const (
+	indexMagic   = "ANAGIDX"
+	indexVersion = 1
+)
+
+// ErrBadIndex is returned by LoadIndex for data that is not a valid index
+var ErrBadIndex = errors.New("invalid anagram index")
+
+const (
+	optFoldCase = 1 << iota
+	optIgnoreSpacePunct
+	optIgnoreMarks
+	optYoAsYe
+)
+
+// Save writes the index in a compact binary form readable by LoadIndex
+func (idx *AnagramIndex) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, 64)
+	putUvarint := func(v uint64) {
+		buf = binary.AppendUvarint(buf[:0], v)
+		bw.Write(buf)
+	}
+	putString := func(s string) {
+		putUvarint(uint64(len(s)))
+		bw.WriteString(s)
+	}
+
+	bw.WriteString(indexMagic)
+	bw.WriteByte(indexVersion)
+	opts := idx.matcher.opts
+	var flags byte
+	for _, opt := range []struct {
+		set  bool
+		flag byte
+	}{{opts.FoldCase, optFoldCase}, {opts.IgnoreSpacePunct, optIgnoreSpacePunct}, {opts.IgnoreMarks, optIgnoreMarks}, {opts.YoAsYe, optYoAsYe}} {
+		if opt.set {
+			flags |= opt.flag
+		}
+	}
+	bw.WriteByte(flags)
+	bw.WriteByte(byte(opts.Normalization))
+
+	alphabet := make([]rune, len(idx.alphabet))
+	for r, i := range idx.alphabet {
+		alphabet[i] = r
+	}
+	putUvarint(uint64(len(alphabet)))
+	for _, r := range alphabet {
+		putUvarint(uint64(r))
+	}
+
+	putUvarint(uint64(len(idx.groups)))
+	for i, g := range idx.groups {
+		buf = binary.LittleEndian.AppendUint64(buf[:0], idx.hashes[i])
+		bw.Write(buf)
+		putUvarint(uint64(len(g.Words)))
+		for _, word := range g.Words {
+			putString(word)
+		}
+		// Ключ - одно из слов множества, сохраняется его номер
+		key := 0
+		for j, word := range g.Words {
+			if word == g.Key {
+				key = j
+			}
+		}
+		putUvarint(uint64(key))
+	}
+
+	putUvarint(uint64(len(idx.nodes)))
+	for _, n := range idx.nodes {
+		putUvarint(uint64(n.group + 1))
+		putUvarint(uint64(len(n.children)))
+		for _, e := range n.children {
+			putUvarint(uint64(e.letter))
+			putUvarint(uint64(e.count))
+			putUvarint(uint64(e.node))
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadIndex reads an index written by Save
+func LoadIndex(r io.Reader) (*AnagramIndex, error) {
+	d := indexDecoder{r: bufio.NewReader(r)}
+	idx, err := d.decode()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("%w: %w", ErrBadIndex, err)
+	}
+	return idx, nil
+}
+
+type indexDecoder struct {
+	r *bufio.Reader
+}
+
+// count читает размер и проверяет его; limit защищает от огромных выделений памяти на повреждённых данных
+func (d *indexDecoder) count(limit uint64) (int, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, err
+	}
+	if n > limit {
+		return 0, fmt.Errorf("value %d out of range", n)
+	}
+	return int(n), nil
+}
+
+func (d *indexDecoder) decode() (*AnagramIndex, error) {
+	header := make([]byte, len(indexMagic)+3)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return nil, err
+	}
+	if string(header[:len(indexMagic)]) != indexMagic || header[len(indexMagic)] != indexVersion {
+		return nil, errors.New("unknown format or version")
+	}
+	flags, normalization := header[len(indexMagic)+1], Normalization(header[len(indexMagic)+2])
+	if normalization > NormNFKD {
+		return nil, fmt.Errorf("unknown normalization %d", normalization)
+	}
+	idx := &AnagramIndex{
+		matcher: NewMatcher(MatcherOptions{
+			FoldCase:         flags&optFoldCase != 0,
+			Normalization:    normalization,
+			IgnoreSpacePunct: flags&optIgnoreSpacePunct != 0,
+			IgnoreMarks:      flags&optIgnoreMarks != 0,
+			YoAsYe:           flags&optYoAsYe != 0,
+		}),
+		index:    make(map[uint64]int32),
+		alphabet: make(map[rune]int32),
+	}
+
+	letters, err := d.count(1 << 21)
+	if err != nil {
+		return nil, err
+	}
+	for i := range letters {
+		r, err := d.count(0x10FFFF)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := idx.alphabet[rune(r)]; dup {
+			return nil, fmt.Errorf("letter %q repeats in the alphabet", rune(r))
+		}
+		idx.alphabet[rune(r)] = int32(i)
+	}
+
+	groups, err := d.count(1<<31 - 1)
+	if err != nil {
+		return nil, err
+	}
+	for range groups {
+		if err := d.group(idx); err != nil {
+			return nil, err
+		}
+	}
+
+	nodes, err := d.count(1<<31 - 1)
+	if err != nil {
+		return nil, err
+	}
+	if nodes == 0 {
+		return nil, errors.New("missing trie root")
+	}
+	for i := range nodes {
+		group, err := d.count(uint64(groups))
+		if err != nil {
+			return nil, err
+		}
+		node := trieNode{group: int32(group) - 1}
+		children, err := d.count(uint64(letters) * uint64(nodes))
+		if err != nil {
+			return nil, err
+		}
+		for range children {
+			var e [3]int
+			for j, limit := range []int{letters - 1, 1<<31 - 1, nodes - 1} {
+				if e[j], err = d.count(uint64(max(limit, 0))); err != nil {
+					return nil, err
+				}
+			}
+			edge := trieEdge{letter: int32(e[0]), count: int32(e[1]), node: int32(e[2])}
+			if err := checkEdge(node.children, edge, i, letters); err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, edge)
+		}
+		idx.nodes = append(idx.nodes, node)
+	}
+	return idx, nil
+}
+
+// checkEdge проверяет переход узла parent. Save записывает узлы в порядке создания,
+// поэтому потомок всегда идёт после родителя: это исключает циклы, на которых
+// зациклился бы обход в SubAnagrams. Переходы отсортированы для бинарного поиска в insert
+func checkEdge(prev []trieEdge, e trieEdge, parent, letters int) error {
+	switch {
+	case int(e.letter) >= letters:
+		return fmt.Errorf("node %d: letter %d is not in the alphabet", parent, e.letter)
+	case e.count < 1:
+		return fmt.Errorf("node %d: zero letter count", parent)
+	case int(e.node) <= parent:
+		return fmt.Errorf("node %d: child %d does not follow its parent", parent, e.node)
+	case len(prev) > 0 && compareEdges(prev[len(prev)-1], e) >= 0:
+		return fmt.Errorf("node %d: edges are not in ascending order", parent)
+	}
+	return nil
+}
+
+func (d *indexDecoder) group(idx *AnagramIndex) error {
+	var hash [8]byte
+	if _, err := io.ReadFull(d.r, hash[:]); err != nil {
+		return err
+	}
+	n, err := d.count(1<<31 - 1)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("empty group")
+	}
+	words := make([]string, 0, min(n, 1024))
+	for range n {
+		size, err := d.count(maxWordSize)
+		if err != nil {
+			return err
+		}
+		word := make([]byte, size)
+		if _, err := io.ReadFull(d.r, word); err != nil {
+			return err
+		}
+		words = append(words, string(word))
+	}
+	key, err := d.count(uint64(n - 1))
+	if err != nil {
+		return err
+	}
+	idx.addGroup(Group{Key: words[key], Words: words}, binary.LittleEndian.Uint64(hash[:]))
+	return nil
+}
@@ -1,9 +1,118 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/ntpmonitor"
 	"github.com/pozedorum/WB_project_2.8/task8/pkg/ntpprinttime"
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/sntpserver"
 )
 
 func main() {
-	ntpprinttime.PrintNowTime()
+	daemon := flag.Bool("daemon", false, "poll the server periodically and serve statistics over HTTP")
+	server := flag.String("server", ntpprinttime.DefaultServers[0], "NTP server to query")
+	timeout := flag.Duration("timeout", 5*time.Second, "query timeout")
+	format := flag.String("format", string(ntpprinttime.FormatRFC3339Nano), "output format: rfc3339, unix or json")
+	useNTS := flag.Bool("nts", false, "require NTS authentication, -server is then the NTS-KE server")
+	ntsCA := flag.String("nts-ca", "", "PEM file with certificates trusted for NTS-KE instead of the system roots")
+	interval := flag.Duration("interval", ntpmonitor.DefaultInterval, "poll interval in daemon mode")
+	listen := flag.String("listen", ":8080", "HTTP address for /history and /metrics in daemon mode")
+	history := flag.Int("history", ntpmonitor.DefaultHistorySize, "number of measurements to keep")
+	serve := flag.String("serve", "", "run SNTP server on the given UDP address, e.g. :1123")
+	upstream := flag.String("upstream", "", "comma separated upstream servers for the SNTP server, local clock is used if empty")
+	flag.Parse()
+
+	switch {
+	case *serve != "":
+		if err := runServer(*serve, *upstream); err != nil {
+			fmt.Fprintf(os.Stderr, "server error: %v\n", err)
+			os.Exit(1)
+		}
+	case *daemon:
+		if err := runDaemon(*server, *interval, *listen, *history); err != nil {
+			fmt.Fprintf(os.Stderr, "daemon error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		if err := printTime(*server, *timeout, *format, *useNTS, *ntsCA); err != nil {
+			fmt.Fprintf(os.Stderr, "ntp-server error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func printTime(server string, timeout time.Duration, format string, useNTS bool, caFile string) error {
+	f, err := ntpprinttime.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	opts := ntpprinttime.Options{Server: server, Timeout: timeout, NTS: useNTS}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in " + caFile)
+		}
+		opts.TLSConfig = &tls.Config{RootCAs: pool}
+	}
+	res, err := ntpprinttime.Query(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	return ntpprinttime.WriteResult(os.Stdout, res, f)
+}
+
+func runServer(addr, upstream string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var clock sntpserver.Clock = sntpserver.LocalClock{}
+	if upstream != "" {
+		uc := sntpserver.NewUpstreamClock(ntpprinttime.ConsensusOptions{Servers: strings.Split(upstream, ",")}, time.Minute)
+		go uc.Run(ctx, func(err error) {
+			fmt.Fprintf(os.Stderr, "upstream sync error: %v\n", err)
+		})
+		clock = uc
+	}
+
+	fmt.Fprintf(os.Stderr, "serving SNTP on %s\n", addr)
+	return sntpserver.NewServer(addr, clock).ListenAndServe(ctx)
+}
+
+func runDaemon(server string, interval time.Duration, listen string, history int) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	m := ntpmonitor.NewMonitor(server, interval, history)
+	go m.Run(ctx)
+
+	srv := &http.Server{Addr: listen, Handler: m.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "monitoring %s every %s, statistics on %s\n", server, interval, listen)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
 }
@@ -2,8 +2,9 @@ module github.com/pozedorum/WB_project_2.8/task8
 
 go 1.24.1
 
+require github.com/beevik/ntp v1.4.3
+
 require (
-	github.com/beevik/ntp v1.4.3 // indirect
 	golang.org/x/lint v0.0.0-20241112194109-818c5a804067 // indirect
 	golang.org/x/net v0.41.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,62 @@
+package ntpmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Handler returns an http.Handler with the following endpoints:
+//   - /history - JSON with stats and all measurements
+//   - /metrics - the last state in Prometheus text format
+func (m *Monitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/history", m.serveHistory)
+	mux.HandleFunc("/metrics", m.serveMetrics)
+	return mux
+}
+
+func (m *Monitor) serveHistory(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Stats   Stats         `json:"stats"`
+		History []Measurement `json:"history"`
+	}{m.Stats(), m.History()}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (m *Monitor) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteMetrics(w)
+}
+
+// WriteMetrics writes the current state in Prometheus text exposition format
+func (m *Monitor) WriteMetrics(w io.Writer) {
+	st := m.Stats()
+	history := m.History()
+	label := fmt.Sprintf("{server=%q}", st.Server)
+
+	metric := func(name, typ, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s%s %v\n", name, help, name, typ, name, label, value)
+	}
+
+	metric("ntp_polls_total", "counter", "Total number of NTP queries.", st.Polls)
+	metric("ntp_poll_failures_total", "counter", "Number of failed NTP queries.", st.Failures)
+	metric("ntp_offset_seconds", "gauge", "Last measured offset of the server clock relative to the local clock.", st.LastOffset.Seconds())
+	metric("ntp_rtt_seconds", "gauge", "Last measured round-trip delay.", st.LastRTT.Seconds())
+	metric("ntp_rtt_mean_seconds", "gauge", "Mean round-trip delay over the history.", st.MeanRTT.Seconds())
+	metric("ntp_drift_ppm", "gauge", "Estimated frequency error of the local clock.", st.DriftPPM)
+
+	if len(history) > 0 {
+		last := history[len(history)-1]
+		if last.Error == "" {
+			metric("ntp_stratum", "gauge", "Stratum of the server.", last.Stratum)
+			metric("ntp_root_distance_seconds", "gauge", "Root distance of the last response.", last.RootDistance.Seconds())
+		}
+		metric("ntp_last_poll_timestamp_seconds", "gauge", "Unix time of the last poll.", float64(last.Time.UnixNano())/1e9)
+	}
+}
@@ -0,0 +1,183 @@
+// Package ntpmonitor periodically polls an NTP server and tracks the local clock
+// offset, round-trip delay and drift
+package ntpmonitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+const (
+	DefaultInterval    = time.Minute
+	DefaultHistorySize = 1440 // сутки измерений при интервале в минуту
+)
+
+// Measurement is the result of a single poll
+type Measurement struct {
+	Time           time.Time     `json:"time"`
+	Offset         time.Duration `json:"offset_ns"`
+	RTT            time.Duration `json:"rtt_ns"`
+	RootDistance   time.Duration `json:"root_distance_ns"`
+	RootDelay      time.Duration `json:"root_delay_ns"`
+	RootDispersion time.Duration `json:"root_dispersion_ns"`
+	Stratum        uint8         `json:"stratum"`
+	ReferenceID    string        `json:"reference_id"`
+	Leap           uint8         `json:"leap"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// Stats summarizes the history of measurements
+type Stats struct {
+	Server     string        `json:"server"`
+	Polls      int           `json:"polls"`
+	Failures   int           `json:"failures"`
+	LastOffset time.Duration `json:"last_offset_ns"`
+	LastRTT    time.Duration `json:"last_rtt_ns"`
+	MeanRTT    time.Duration `json:"mean_rtt_ns"`
+	// DriftPPM is the estimated frequency error of the local clock in parts per million,
+	// positive value means the local clock runs fast
+	DriftPPM float64 `json:"drift_ppm"`
+}
+
+// Monitor polls the server and keeps a bounded history of measurements
+type Monitor struct {
+	server   string
+	interval time.Duration
+	size     int
+	query    func(server string) (*ntp.Response, error)
+
+	mu       sync.RWMutex
+	history  []Measurement
+	polls    int
+	failures int
+}
+
+// NewMonitor creates a monitor for server, zero interval and size fall back to defaults
+func NewMonitor(server string, interval time.Duration, size int) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &Monitor{
+		server:   server,
+		interval: interval,
+		size:     size,
+		query:    ntp.Query,
+		history:  make([]Measurement, 0, size),
+	}
+}
+
+// Run polls the server immediately and then on every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	m.Poll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Poll()
+		}
+	}
+}
+
+// Poll performs a single query and records its result
+func (m *Monitor) Poll() Measurement {
+	meas := Measurement{Time: time.Now()}
+	resp, err := m.query(m.server)
+	if err == nil {
+		err = resp.Validate()
+	}
+	if err != nil {
+		meas.Error = err.Error()
+	} else {
+		meas.Offset = resp.ClockOffset
+		meas.RTT = resp.RTT
+		meas.RootDistance = resp.RootDistance
+		meas.RootDelay = resp.RootDelay
+		meas.RootDispersion = resp.RootDispersion
+		meas.Stratum = resp.Stratum
+		meas.ReferenceID = resp.ReferenceString()
+		meas.Leap = uint8(resp.Leap)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.polls++
+	if err != nil {
+		m.failures++
+	}
+	// Старые измерения вытесняются, когда история заполнена
+	if len(m.history) == m.size {
+		copy(m.history, m.history[1:])
+		m.history = m.history[:m.size-1]
+	}
+	m.history = append(m.history, meas)
+	return meas
+}
+
+// History returns a copy of the recorded measurements, oldest first
+func (m *Monitor) History() []Measurement {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make([]Measurement, len(m.history))
+	copy(res, m.history)
+	return res
+}
+
+// Stats returns the summary of the recorded measurements
+func (m *Monitor) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	st := Stats{Server: m.server, Polls: m.polls, Failures: m.failures}
+	var ok []Measurement
+	var sumRTT time.Duration
+	for _, meas := range m.history {
+		if meas.Error == "" {
+			ok = append(ok, meas)
+			sumRTT += meas.RTT
+		}
+	}
+	if len(ok) == 0 {
+		return st
+	}
+	last := ok[len(ok)-1]
+	st.LastOffset = last.Offset
+	st.LastRTT = last.RTT
+	st.MeanRTT = sumRTT / time.Duration(len(ok))
+	st.DriftPPM = estimateDrift(ok)
+	return st
+}
+
+// estimateDrift fits offset(t) with a line by least squares and returns its slope in ppm.
+// Offset is server time minus local time, so a growing offset means the local clock is slow.
+func estimateDrift(ms []Measurement) float64 {
+	if len(ms) < 2 {
+		return 0
+	}
+	start := ms[0].Time
+	n := float64(len(ms))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, meas := range ms {
+		x := meas.Time.Sub(start).Seconds()
+		y := meas.Offset.Seconds()
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	return -slope * 1e6
+}
@@ -0,0 +1,114 @@
+package ntpmonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+func fakeQuery(offsets []time.Duration) func(string) (*ntp.Response, error) {
+	ind := 0
+	return func(string) (*ntp.Response, error) {
+		if ind >= len(offsets) {
+			return nil, errors.New("timeout")
+		}
+		now := time.Now()
+		resp := &ntp.Response{
+			Time:          now,
+			ReferenceTime: now,
+			ClockOffset:   offsets[ind],
+			RTT:           10 * time.Millisecond,
+			Stratum:       2,
+		}
+		ind++
+		return resp, nil
+	}
+}
+
+func TestMonitorHistory(t *testing.T) {
+	m := NewMonitor("test", time.Second, 3)
+	m.query = fakeQuery([]time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond})
+
+	for range 5 {
+		m.Poll()
+	}
+
+	history := m.History()
+	if len(history) != 3 {
+		t.Fatalf("expected history of 3, got %d", len(history))
+	}
+	if history[0].Offset != 3*time.Millisecond || history[2].Error == "" {
+		t.Errorf("wrong history: %+v", history)
+	}
+
+	st := m.Stats()
+	if st.Polls != 5 || st.Failures != 1 {
+		t.Errorf("wrong counters: %+v", st)
+	}
+	if st.LastOffset != 4*time.Millisecond || st.MeanRTT != 10*time.Millisecond {
+		t.Errorf("wrong stats: %+v", st)
+	}
+}
+
+func TestEstimateDrift(t *testing.T) {
+	start := time.Now()
+	ms := make([]Measurement, 0)
+	// Часы отстают на 50 мкс в секунду, т.е. смещение растёт
+	for i := range 10 {
+		ms = append(ms, Measurement{
+			Time:   start.Add(time.Duration(i) * time.Second),
+			Offset: time.Duration(i) * 50 * time.Microsecond,
+		})
+	}
+	if drift := estimateDrift(ms); math.Abs(drift+50) > 1e-6 {
+		t.Errorf("expected drift -50 ppm, got %v", drift)
+	}
+	if drift := estimateDrift(ms[:1]); drift != 0 {
+		t.Errorf("expected zero drift for one sample, got %v", drift)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := NewMonitor("pool.test", time.Second, 10)
+	m.query = fakeQuery([]time.Duration{5 * time.Millisecond})
+	m.Poll()
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Stats   Stats         `json:"stats"`
+		History []Measurement `json:"history"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.History) != 1 || body.Stats.LastOffset != 5*time.Millisecond {
+		t.Errorf("wrong body: %+v", body)
+	}
+
+	var sb strings.Builder
+	m.WriteMetrics(&sb)
+	for _, want := range []string{
+		"# TYPE ntp_offset_seconds gauge",
+		`ntp_offset_seconds{server="pool.test"} 0.005`,
+		`ntp_polls_total{server="pool.test"} 1`,
+		`ntp_stratum{server="pool.test"} 2`,
+	} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, sb.String())
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ntpprinttime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+// DefaultServers is the list of servers used when ConsensusOptions.Servers is empty
+var DefaultServers = []string{
+	"0.beevik-ntp.pool.ntp.org",
+	"1.beevik-ntp.pool.ntp.org",
+	"2.beevik-ntp.pool.ntp.org",
+	"3.beevik-ntp.pool.ntp.org",
+}
+
+const defaultQueryTimeout = 5 * time.Second
+
+var (
+	// ErrNoServers is returned when no server answered with a valid response
+	ErrNoServers = errors.New("no ntp server answered")
+	// ErrNoConsensus is returned when the majority of servers do not agree on the time
+	ErrNoConsensus = errors.New("ntp servers do not agree on the time")
+)
+
+// ConsensusOptions configures QueryConsensus
+type ConsensusOptions struct {
+	Servers []string      // адреса серверов в формате host или host:port
+	Timeout time.Duration // таймаут на запрос к одному серверу
+	// MinSources is the minimal number of valid answers required, defaults to 1
+	MinSources int
+}
+
+// Sample is the answer of a single server
+type Sample struct {
+	Server     string
+	Address    net.IP        // адрес, ответивший на запрос
+	Offset     time.Duration // смещение локальных часов относительно сервера
+	RTT        time.Duration
+	RootDelay  time.Duration // задержка от сервера до первичного источника
+	ErrorBound time.Duration // половина ширины интервала корректности
+	Stratum    uint8
+	Err        error
+	Truechimer bool
+}
+
+// Consensus is the time agreed by the majority of servers
+type Consensus struct {
+	Time       time.Time     // локальное время, скорректированное на Offset
+	Offset     time.Duration // середина пересечения интервалов
+	ErrorBound time.Duration // истинное смещение лежит в Offset ± ErrorBound
+	Samples    []Sample
+}
+
+// Falsechimers returns samples rejected by the intersection algorithm
+func (c *Consensus) Falsechimers() []Sample {
+	res := make([]Sample, 0)
+	for _, s := range c.Samples {
+		if s.Err == nil && !s.Truechimer {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// QueryConsensus queries all servers in parallel and returns the time agreed by
+// the majority of them. Servers whose correctness intervals do not intersect
+// with the majority (falsechimers) are rejected by Marzullo's algorithm.
+func QueryConsensus(ctx context.Context, opts ConsensusOptions) (*Consensus, error) {
+	servers := opts.Servers
+	if len(servers) == 0 {
+		servers = DefaultServers
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	minSources := opts.MinSources
+	if minSources < 1 {
+		minSources = 1
+	}
+
+	type indexed struct {
+		ind    int
+		sample Sample
+	}
+	resCh := make(chan indexed, len(servers)) // буферизованный, чтобы горутины не зависли после отмены
+	for ind, server := range servers {
+		go func() {
+			resCh <- indexed{ind, querySample(ctx, server, timeout)}
+		}()
+	}
+
+	samples := make([]Sample, len(servers))
+	for range servers {
+		select {
+		case res := <-resCh:
+			samples[res.ind] = res.sample
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return selectConsensus(samples, minSources)
+}
+
+func querySample(ctx context.Context, server string, timeout time.Duration) Sample {
+	sample := Sample{Server: server}
+	res, err := Query(ctx, Options{Server: server, Timeout: timeout})
+	if err != nil {
+		sample.Err = err
+		return sample
+	}
+	sample.Address = res.Address
+	sample.Offset = res.Offset
+	sample.RTT = res.RTT
+	sample.RootDelay = res.RootDelay
+	sample.ErrorBound = res.RootDistance
+	sample.Stratum = res.Stratum
+	return sample
+}
+
+type edge struct {
+	value time.Duration
+	lower bool
+}
+
+// selectConsensus runs the intersection algorithm over valid samples
+func selectConsensus(samples []Sample, minSources int) (*Consensus, error) {
+	edges := make([]edge, 0, 2*len(samples))
+	var errs []error
+	for _, s := range samples {
+		if s.Err != nil {
+			errs = append(errs, s.Err)
+			continue
+		}
+		edges = append(edges,
+			edge{s.Offset - s.ErrorBound, true},
+			edge{s.Offset + s.ErrorBound, false})
+	}
+	valid := len(edges) / 2
+	if valid == 0 {
+		return nil, errors.Join(append([]error{ErrNoServers}, errs...)...)
+	}
+	if valid < minSources {
+		return nil, fmt.Errorf("%w: got %d valid answers, need %d", ErrNoServers, valid, minSources)
+	}
+
+	// При равенстве значений нижняя граница идёт раньше верхней, чтобы касающиеся интервалы пересекались
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].value != edges[j].value {
+			return edges[i].value < edges[j].value
+		}
+		return edges[i].lower && !edges[j].lower
+	})
+
+	// Перебираем допустимое число лжецов, пока они в меньшинстве
+	for falsechimers := 0; 2*falsechimers < valid; falsechimers++ {
+		need := valid - falsechimers
+		low, lowOK := scanEdges(edges, need, true)
+		high, highOK := scanEdges(edges, need, false)
+		if !lowOK || !highOK || low > high {
+			continue
+		}
+
+		c := &Consensus{
+			Offset:     low + (high-low)/2,
+			ErrorBound: (high - low) / 2,
+			Samples:    samples,
+		}
+		for i := range c.Samples {
+			s := &c.Samples[i]
+			s.Truechimer = s.Err == nil && s.Offset-s.ErrorBound <= high && s.Offset+s.ErrorBound >= low
+		}
+		c.Time = time.Now().Add(c.Offset)
+		return c, nil
+	}
+
+	return nil, ErrNoConsensus
+}
+
+// scanEdges returns the first point covered by at least need intervals.
+// Edges are scanned from the left when ascending is true and from the right otherwise.
+func scanEdges(edges []edge, need int, ascending bool) (time.Duration, bool) {
+	count := 0
+	for i := range edges {
+		e := edges[i]
+		if !ascending {
+			e = edges[len(edges)-1-i]
+		}
+		if e.lower == ascending {
+			count++
+			if count >= need {
+				return e.value, true
+			}
+		} else {
+			count--
+		}
+	}
+	return 0, false
+}
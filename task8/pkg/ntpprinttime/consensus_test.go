@@ -0,0 +1,188 @@
+package ntpprinttime
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+const ntpEpochOffset = 2208988800 // секунды между 1900 и 1970 годами
+
+func toNTP(t time.Time) uint64 {
+	nsec := uint64(t.Sub(time.Unix(-ntpEpochOffset, 0)))
+	sec := nsec / 1e9
+	frac := (nsec % 1e9) << 32 / 1e9
+	return sec<<32 | frac
+}
+
+// startRawServer starts a local UDP server answering every request with handle(req)
+func startRawServer(t *testing.T, handle func(req []byte) []byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp := handle(buf[:n])
+			if resp == nil {
+				continue
+			}
+			if _, err := conn.WriteTo(resp, addr); err != nil {
+				return
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// fakeResponse builds a valid server answer to req with the clock shifted by offset
+func fakeResponse(req []byte, offset time.Duration) []byte {
+	if len(req) < 48 {
+		return nil
+	}
+	now := time.Now().Add(offset)
+	resp := make([]byte, 48)
+	resp[0] = 0<<6 | 4<<3 | 4                    // LI = 0, VN = 4, Mode = server
+	resp[1] = 2                                  // stratum
+	resp[3] = 0xec                               // precision 2^-20
+	binary.BigEndian.PutUint32(resp[8:], 0x0290) // root dispersion ~10ms
+	copy(resp[12:], "TEST")
+	binary.BigEndian.PutUint64(resp[16:], toNTP(now.Add(-time.Second)))
+	copy(resp[24:32], req[40:48])
+	binary.BigEndian.PutUint64(resp[32:], toNTP(now))
+	binary.BigEndian.PutUint64(resp[40:], toNTP(now))
+	return resp
+}
+
+// startFakeServer starts a local NTP stand-in whose clock is shifted by offset
+func startFakeServer(t *testing.T, offset time.Duration) string {
+	t.Helper()
+	return startRawServer(t, func(req []byte) []byte { return fakeResponse(req, offset) })
+}
+
+func TestQueryConsensus(t *testing.T) {
+	good1 := startFakeServer(t, 0)
+	good2 := startFakeServer(t, 2*time.Millisecond)
+	good3 := startFakeServer(t, -2*time.Millisecond)
+	bad := startFakeServer(t, time.Hour)
+
+	c, err := QueryConsensus(context.Background(), ConsensusOptions{
+		Servers: []string{good1, bad, good2, good3},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Offset < -20*time.Millisecond || c.Offset > 20*time.Millisecond {
+		t.Errorf("wrong offset: %v", c.Offset)
+	}
+	if c.ErrorBound <= 0 || c.ErrorBound > 20*time.Millisecond {
+		t.Errorf("wrong error bound: %v", c.ErrorBound)
+	}
+	liars := c.Falsechimers()
+	if len(liars) != 1 || liars[0].Server != bad {
+		t.Errorf("expected %s to be the only falsechimer, got %+v", bad, liars)
+	}
+}
+
+func TestQueryConsensusNoMajority(t *testing.T) {
+	good := startFakeServer(t, 0)
+	bad := startFakeServer(t, time.Hour)
+
+	_, err := QueryConsensus(context.Background(), ConsensusOptions{
+		Servers: []string{good, bad},
+		Timeout: time.Second,
+	})
+	if !errors.Is(err, ErrNoConsensus) {
+		t.Errorf("expected ErrNoConsensus, got %v", err)
+	}
+}
+
+func TestQueryConsensusUnreachable(t *testing.T) {
+	good := startFakeServer(t, 0)
+
+	// Порт, на котором никто не слушает
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	dead := conn.LocalAddr().String()
+	conn.Close()
+
+	c, err := QueryConsensus(context.Background(), ConsensusOptions{
+		Servers: []string{good, dead},
+		Timeout: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Samples[1].Err == nil {
+		t.Errorf("expected error for %s", dead)
+	}
+
+	_, err = QueryConsensus(context.Background(), ConsensusOptions{
+		Servers:    []string{good, dead},
+		Timeout:    200 * time.Millisecond,
+		MinSources: 2,
+	})
+	if !errors.Is(err, ErrNoServers) {
+		t.Errorf("expected ErrNoServers, got %v", err)
+	}
+}
+
+func TestSelectConsensus(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		samples []Sample
+		offset  time.Duration
+		bound   time.Duration
+		err     error
+	}{
+		{"single", []Sample{{Offset: 5 * ms, ErrorBound: ms}}, 5 * ms, ms, nil},
+		{"intersection", []Sample{
+			{Offset: 10 * ms, ErrorBound: 2 * ms},
+			{Offset: 11 * ms, ErrorBound: 2 * ms},
+			{Offset: 12 * ms, ErrorBound: 2 * ms},
+		}, 11 * ms, ms, nil},
+		{"touching", []Sample{
+			{Offset: 0, ErrorBound: ms},
+			{Offset: 2 * ms, ErrorBound: ms},
+		}, ms, 0, nil},
+		{"one liar", []Sample{
+			{Offset: 10 * ms, ErrorBound: 2 * ms},
+			{Offset: 11 * ms, ErrorBound: 2 * ms},
+			{Offset: 900 * ms, ErrorBound: 2 * ms},
+		}, 10*ms + ms/2, 3 * ms / 2, nil},
+		{"no majority", []Sample{
+			{Offset: 0, ErrorBound: ms},
+			{Offset: 100 * ms, ErrorBound: ms},
+		}, 0, 0, ErrNoConsensus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := selectConsensus(tt.samples, 1)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("wrong error\nexpected: %v\nactual: %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if c.Offset != tt.offset || c.ErrorBound != tt.bound {
+				t.Errorf("expected %v ± %v, got %v ± %v", tt.offset, tt.bound, c.Offset, c.ErrorBound)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package ntpprinttime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format is the output format of WriteResult
+type Format string
+
+const (
+	FormatRFC3339Nano Format = "rfc3339"
+	FormatUnix        Format = "unix"
+	FormatJSON        Format = "json"
+)
+
+// ParseFormat checks that s is one of the supported formats
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatRFC3339Nano, FormatUnix, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected rfc3339, unix or json", s)
+}
+
+// WriteResult writes res to w in the given format
+func WriteResult(w io.Writer, res *Result, format Format) error {
+	var err error
+	switch format {
+	case FormatRFC3339Nano, "":
+		_, err = fmt.Fprintln(w, res.Time.Format(time.RFC3339Nano))
+	case FormatUnix:
+		// секунды и наносекунды без потери точности float64
+		_, err = fmt.Fprintf(w, "%d.%09d\n", res.Time.Unix(), res.Time.Nanosecond())
+	case FormatJSON:
+		err = json.NewEncoder(w).Encode(res)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	return err
+}
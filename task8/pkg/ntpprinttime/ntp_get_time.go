@@ -1,19 +1,20 @@
 package ntpprinttime
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
-
-	"github.com/beevik/ntp"
 )
 
 // PrintNowTime print current time in a RFC3339Nano format
+//
+// Deprecated: exits the process on error, use Query and WriteResult instead.
 func PrintNowTime() {
-	nowTime, err := ntp.Time("0.beevik-ntp.pool.ntp.org")
+	res, err := Query(context.Background(), Options{})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ntp-server error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("now time is %s\n", nowTime.Format(time.RFC3339Nano))
+	fmt.Printf("now time is %s\n", res.Time.Format(time.RFC3339Nano))
 }
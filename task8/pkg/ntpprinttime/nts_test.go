@@ -0,0 +1,128 @@
+package ntpprinttime
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/nts"
+)
+
+// startNTSStandIn starts local NTS-KE and NTP servers with a self-signed certificate
+// and returns the KE address with a client config trusting the certificate
+func startNTSStandIn(t *testing.T) (string, *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	srv, err := nts.NewServer(
+		&tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+		func(req []byte, _ time.Time) ([]byte, bool) { return fakeResponse(req, 0), true },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.NTPAddr = udp.LocalAddr().String()
+	go func() { _ = srv.ServeNTP(ctx, udp) }()
+	go func() { _ = srv.ServeKE(ctx, tcp) }()
+
+	return tcp.Addr().String(), &tls.Config{RootCAs: pool}
+}
+
+func TestQueryNTS(t *testing.T) {
+	keAddr, clientCfg := startNTSStandIn(t)
+
+	res, err := Query(context.Background(), Options{Server: keAddr, Timeout: time.Second, NTS: true, TLSConfig: clientCfg})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Authenticated {
+		t.Error("result must be authenticated")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResult(&buf, res, FormatJSON); err != nil || !bytes.Contains(buf.Bytes(), []byte(`"authenticated":true`)) {
+		t.Errorf("json output does not report authentication: %s %v", buf.String(), err)
+	}
+}
+
+func TestQueryNTSRequired(t *testing.T) {
+	keAddr, _ := startNTSStandIn(t)
+	plain := startFakeServer(t, 0)
+
+	// Сертификат не доверенный
+	_, err := Query(context.Background(), Options{Server: keAddr, Timeout: time.Second, NTS: true})
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) || errors.Is(err, context.Canceled) {
+		t.Errorf("expected untrusted certificate error, got %v", err)
+	}
+
+	// Обычный NTP сервер не поддерживает NTS-KE: TCP порт закрыт
+	_, err = Query(context.Background(), Options{Server: plain, Timeout: time.Second, NTS: true})
+	if !errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, context.Canceled) {
+		t.Errorf("expected connection refused, got %v", err)
+	}
+}
+
+func TestQueryNTSTimeout(t *testing.T) {
+	// Сервер принимает соединение и молчит
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+		}
+	}()
+
+	start := time.Now()
+	_, err = Query(context.Background(), Options{Server: ln.Addr().String(), Timeout: 200 * time.Millisecond, NTS: true})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("key exchange was not limited by the timeout: %v", elapsed)
+	}
+}
@@ -0,0 +1,185 @@
+package ntpprinttime
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/nts"
+)
+
+// ErrTimeout is returned when the server did not answer in time
+var ErrTimeout = errors.New("ntp query timed out")
+
+// KissOfDeathError is returned when the server answered with a kiss-of-death packet (stratum 0)
+type KissOfDeathError struct {
+	Server string
+	Code   string // например RATE, DENY или RSTR
+}
+
+func (e *KissOfDeathError) Error() string {
+	return fmt.Sprintf("%s: kiss of death received: %s", e.Server, e.Code)
+}
+
+// Unwrap allows matching with errors.Is(err, ntp.ErrKissOfDeath)
+func (e *KissOfDeathError) Unwrap() error { return ntp.ErrKissOfDeath }
+
+// InvalidResponseError is returned when the answer is malformed or not suitable for synchronization
+type InvalidResponseError struct {
+	Server string
+	Err    error
+}
+
+func (e *InvalidResponseError) Error() string {
+	return fmt.Sprintf("%s: invalid response: %v", e.Server, e.Err)
+}
+
+func (e *InvalidResponseError) Unwrap() error { return e.Err }
+
+// Options configures Query
+type Options struct {
+	Server  string        // host или host:port, по умолчанию первый из DefaultServers
+	Timeout time.Duration // по умолчанию 5 секунд, ограничивается дедлайном контекста
+	Version int           // версия протокола 2-4, по умолчанию 4
+
+	// NTS requires authentication with Network Time Security (RFC 8915). Server is then
+	// the NTS-KE address (default port 4460) and the NTP server is taken from the key exchange.
+	NTS bool
+	// TLSConfig is used for NTS-KE, nil means the system root certificates
+	TLSConfig *tls.Config
+}
+
+// Result is the validated answer of an NTP server
+type Result struct {
+	Server       string            `json:"server"`
+	Address      net.IP            `json:"address"`   // адрес, на который ушёл запрос
+	Time         time.Time         `json:"time"`      // локальное время, скорректированное на Offset
+	Offset       time.Duration     `json:"offset_ns"` // время сервера минус локальное время
+	RTT          time.Duration     `json:"rtt_ns"`
+	RootDelay    time.Duration     `json:"root_delay_ns"`
+	RootDistance time.Duration     `json:"root_distance_ns"`
+	Stratum      uint8             `json:"stratum"`
+	ReferenceID  string            `json:"reference_id"`
+	Leap         ntp.LeapIndicator `json:"leap"`
+	Precision    time.Duration     `json:"precision_ns"`
+	// Authenticated is true when the answer was verified with NTS
+	Authenticated bool `json:"authenticated"`
+}
+
+// Query asks a single server for the time and validates the answer.
+// Errors are ErrTimeout, *KissOfDeathError, *InvalidResponseError or the context error.
+func Query(ctx context.Context, opts Options) (*Result, error) {
+	server := opts.Server
+	if server == "" {
+		server = DefaultServers[0]
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultQueryTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Соединение закрывается при отмене контекста, чтобы не ждать таймаута
+	stop := func() bool { return false }
+	var address net.IP
+	dialer := func(localAddress, remoteAddress string) (net.Conn, error) {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "udp", remoteAddress)
+		if err != nil {
+			return nil, err
+		}
+		stop = context.AfterFunc(ctx, func() { conn.Close() })
+		if addr, ok := conn.RemoteAddr().(*net.UDPAddr); ok {
+			address = addr.IP
+		}
+		return conn, nil
+	}
+
+	ntpOpts := ntp.QueryOptions{
+		Timeout: timeout,
+		Version: opts.Version,
+		Dialer:  dialer,
+	}
+	ntpServer := server
+	if opts.NTS {
+		// Обмен ключами ограничен тем же таймаутом, что и запрос времени
+		keCtx, cancel := context.WithTimeout(ctx, timeout)
+		session, err := nts.KeyExchange(keCtx, server, opts.TLSConfig)
+		if err != nil {
+			// Ошибка классифицируется до cancel, иначе keCtx всегда отменён и причина теряется
+			err = classifyError(keCtx, server, err)
+			cancel()
+			return nil, err
+		}
+		cancel()
+		ext, err := session.Extension()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", server, err)
+		}
+		ntpOpts.Extensions = []ntp.Extension{ext}
+		ntpServer = session.Server
+	}
+
+	resp, err := ntp.QueryWithOptions(ntpServer, ntpOpts)
+	stop()
+	if err != nil {
+		return nil, classifyError(ctx, server, err)
+	}
+	if err := resp.Validate(); err != nil {
+		if errors.Is(err, ntp.ErrKissOfDeath) {
+			return nil, &KissOfDeathError{Server: server, Code: resp.KissCode}
+		}
+		return nil, &InvalidResponseError{Server: server, Err: err}
+	}
+
+	return &Result{
+		Server:       server,
+		Address:      address,
+		Time:         time.Now().Add(resp.ClockOffset),
+		Offset:       resp.ClockOffset,
+		RTT:          resp.RTT,
+		RootDelay:    resp.RootDelay,
+		RootDistance: resp.RootDistance,
+		Stratum:      resp.Stratum,
+		ReferenceID:  resp.ReferenceString(),
+		Leap:         resp.Leap,
+		Precision:    resp.Precision,
+
+		Authenticated: opts.NTS,
+	}, nil
+}
+
+func classifyError(ctx context.Context, server string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return fmt.Errorf("%s: %w: %w", server, ErrTimeout, ctxErr)
+		}
+		return ctxErr
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%s: %w", server, ErrTimeout)
+	}
+
+	switch {
+	case errors.Is(err, ntp.ErrInvalidMode),
+		errors.Is(err, ntp.ErrInvalidTransmitTime),
+		errors.Is(err, ntp.ErrServerResponseMismatch),
+		errors.Is(err, ntp.ErrServerTickedBackwards),
+		errors.Is(err, ntp.ErrAuthFailed),
+		errors.Is(err, nts.ErrNotAuthenticated),
+		errors.Is(err, nts.ErrNAK):
+		return &InvalidResponseError{Server: server, Err: err}
+	}
+	return fmt.Errorf("%s: %w", server, err)
+}
@@ -0,0 +1,149 @@
+package ntpprinttime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+func TestQuery(t *testing.T) {
+	addr := startFakeServer(t, time.Minute)
+
+	res, err := Query(context.Background(), Options{Server: addr, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := res.Offset - time.Minute; diff < -10*time.Millisecond || diff > 10*time.Millisecond {
+		t.Errorf("expected offset of 1m, got %v", res.Offset)
+	}
+	// Для stratum 2 идентификатор "TEST" читается как IPv4 адрес
+	if res.Stratum != 2 || res.ReferenceID != "84.69.83.84" {
+		t.Errorf("wrong stratum or reference: %d %s", res.Stratum, res.ReferenceID)
+	}
+	if res.Leap != ntp.LeapNoWarning {
+		t.Errorf("wrong leap indicator: %v", res.Leap)
+	}
+	if res.Precision <= 0 || res.Precision > 2*time.Microsecond {
+		t.Errorf("wrong precision: %v", res.Precision)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	silent := startRawServer(t, func([]byte) []byte { return nil })
+	kod := startRawServer(t, func(req []byte) []byte {
+		resp := fakeResponse(req, 0)
+		resp[1] = 0 // stratum 0 - kiss-of-death
+		copy(resp[12:], "RATE")
+		return resp
+	})
+	wrongMode := startRawServer(t, func(req []byte) []byte {
+		resp := fakeResponse(req, 0)
+		resp[0] = 4<<3 | 3 // mode client
+		return resp
+	})
+	notSynced := startRawServer(t, func(req []byte) []byte {
+		resp := fakeResponse(req, 0)
+		resp[0] |= 3 << 6
+		return resp
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := Query(context.Background(), Options{Server: silent, Timeout: 100 * time.Millisecond})
+		if !errors.Is(err, ErrTimeout) {
+			t.Errorf("expected ErrTimeout, got %v", err)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err := Query(ctx, Options{Server: silent})
+		if !errors.Is(err, ErrTimeout) {
+			t.Errorf("expected ErrTimeout, got %v", err)
+		}
+	})
+
+	t.Run("context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := Query(ctx, Options{Server: silent, Timeout: 5 * time.Second})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("query was not interrupted by cancel")
+		}
+	})
+
+	t.Run("kiss of death", func(t *testing.T) {
+		_, err := Query(context.Background(), Options{Server: kod, Timeout: time.Second})
+		var kodErr *KissOfDeathError
+		if !errors.As(err, &kodErr) || kodErr.Code != "RATE" {
+			t.Errorf("expected KissOfDeathError with RATE, got %v", err)
+		}
+		if !errors.Is(err, ntp.ErrKissOfDeath) {
+			t.Errorf("expected to match ntp.ErrKissOfDeath")
+		}
+	})
+
+	for name, addr := range map[string]string{"wrong mode": wrongMode, "not synced": notSynced} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Query(context.Background(), Options{Server: addr, Timeout: time.Second})
+			var invErr *InvalidResponseError
+			if !errors.As(err, &invErr) {
+				t.Errorf("expected InvalidResponseError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	res := &Result{
+		Server:  "test",
+		Time:    time.Date(2024, 5, 1, 12, 30, 0, 1500, time.UTC),
+		Offset:  time.Millisecond,
+		Stratum: 2,
+	}
+
+	tests := []struct {
+		format Format
+		output string
+	}{
+		{FormatRFC3339Nano, "2024-05-01T12:30:00.0000015Z\n"},
+		{FormatUnix, "1714566600.000001500\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResult(&buf, res, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.output {
+				t.Errorf("wrong output\nexpected: %q\nactual: %q", tt.output, buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResult(&buf, res, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if !decoded.Time.Equal(res.Time) || decoded.Offset != res.Offset || decoded.Stratum != 2 {
+		t.Errorf("wrong decoded result: %+v", decoded)
+	}
+
+	if _, err := ParseFormat("xml"); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("expected error for unknown format, got %v", err)
+	}
+}
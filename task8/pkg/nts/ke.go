@@ -0,0 +1,222 @@
+// Package nts implements the client side of Network Time Security for NTP (RFC 8915)
+package nts
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+const (
+	DefaultKEPort  = 4460
+	DefaultNTPPort = 123
+
+	alpnProtocol   = "ntske/1"
+	exporterLabel  = "EXPORTER-network-time-security"
+	protocolNTPv4  = 0
+	keyLen         = 32 // длина ключа AEAD_AES_SIV_CMAC_256
+	criticalBit    = 0x8000
+	maxRecordCount = 1024
+)
+
+// Типы записей NTS-KE (RFC 8915, раздел 4.1)
+const (
+	recEndOfMessage = 0
+	recNextProtocol = 1
+	recError        = 2
+	recWarning      = 3
+	recAEAD         = 4
+	recNewCookie    = 5
+	recServer       = 6
+	recPort         = 7
+)
+
+var (
+	// ErrNoCookies is returned when the session has no cookies left, a new key exchange is required
+	ErrNoCookies = errors.New("nts: no cookies left")
+	// ErrBadKEResponse is returned when the NTS-KE server response violates the protocol
+	ErrBadKEResponse = errors.New("nts: invalid key exchange response")
+)
+
+// KEError is the error record sent by the NTS-KE server
+type KEError struct {
+	Code uint16 // 0 - неизвестная критическая запись, 1 - некорректный запрос, 2 - внутренняя ошибка
+}
+
+func (e *KEError) Error() string {
+	return fmt.Sprintf("nts: key exchange error code %d", e.Code)
+}
+
+// Session holds the keys and cookies obtained by the key exchange
+type Session struct {
+	// Server is the host:port of the NTP server to query
+	Server string
+
+	c2s []byte
+	s2c []byte
+
+	mu      sync.Mutex
+	cookies [][]byte
+}
+
+// KeyExchange performs NTS-KE with the server at address (host or host:port, default port 4460).
+// TLS 1.3 and the "ntske/1" ALPN are enforced over the given config, nil config uses the system roots.
+func KeyExchange(ctx context.Context, address string, config *tls.Config) (*Session, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, strconv.Itoa(DefaultKEPort)
+	}
+
+	cfg := &tls.Config{}
+	if config != nil {
+		cfg = config.Clone()
+	}
+	cfg.MinVersion = tls.VersionTLS13
+	cfg.NextProtos = []string{alpnProtocol}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	d := tls.Dialer{Config: cfg}
+	rawConn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("nts: key exchange with %s: %w", address, err)
+	}
+	conn := rawConn.(*tls.Conn)
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	if conn.ConnectionState().NegotiatedProtocol != alpnProtocol {
+		return nil, fmt.Errorf("%w: server did not negotiate %s", ErrBadKEResponse, alpnProtocol)
+	}
+
+	if _, err := conn.Write(keRequest()); err != nil {
+		return nil, err
+	}
+
+	s := &Session{Server: net.JoinHostPort(host, strconv.Itoa(DefaultNTPPort))}
+	if err := s.readResponse(conn, host); err != nil {
+		return nil, err
+	}
+
+	state := conn.ConnectionState()
+	if s.c2s, err = exportKey(state, 0); err != nil {
+		return nil, err
+	}
+	if s.s2c, err = exportKey(state, 1); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func keRequest() []byte {
+	var buf []byte
+	buf = appendRecord(buf, recNextProtocol, true, binary.BigEndian.AppendUint16(nil, protocolNTPv4))
+	buf = appendRecord(buf, recAEAD, true, binary.BigEndian.AppendUint16(nil, aeadAESSIVCMAC256))
+	buf = appendRecord(buf, recEndOfMessage, true, nil)
+	return buf
+}
+
+func appendRecord(buf []byte, typ uint16, critical bool, body []byte) []byte {
+	if critical {
+		typ |= criticalBit
+	}
+	buf = binary.BigEndian.AppendUint16(buf, typ)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(body)))
+	return append(buf, body...)
+}
+
+// readResponse parses the server records up to End of Message
+func (s *Session) readResponse(r io.Reader, host string) error {
+	var protoOK, aeadOK bool
+	ntpHost, ntpPort := host, strconv.Itoa(DefaultNTPPort)
+
+	hdr := make([]byte, 4)
+	for range maxRecordCount {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return fmt.Errorf("%w: %w", ErrBadKEResponse, err)
+		}
+		critical := binary.BigEndian.Uint16(hdr)&criticalBit != 0
+		typ := binary.BigEndian.Uint16(hdr) &^ criticalBit
+		body := make([]byte, binary.BigEndian.Uint16(hdr[2:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return fmt.Errorf("%w: %w", ErrBadKEResponse, err)
+		}
+
+		switch typ {
+		case recEndOfMessage:
+			if !protoOK || !aeadOK {
+				return fmt.Errorf("%w: protocol or AEAD algorithm not negotiated", ErrBadKEResponse)
+			}
+			if len(s.cookies) == 0 {
+				return fmt.Errorf("%w: no cookies", ErrBadKEResponse)
+			}
+			s.Server = net.JoinHostPort(ntpHost, ntpPort)
+			return nil
+		case recNextProtocol:
+			protoOK = len(body) == 2 && binary.BigEndian.Uint16(body) == protocolNTPv4
+		case recError:
+			if len(body) != 2 {
+				return ErrBadKEResponse
+			}
+			return &KEError{Code: binary.BigEndian.Uint16(body)}
+		case recWarning:
+			// предупреждения не мешают работе
+		case recAEAD:
+			aeadOK = len(body) == 2 && binary.BigEndian.Uint16(body) == aeadAESSIVCMAC256
+		case recNewCookie:
+			s.cookies = append(s.cookies, body)
+		case recServer:
+			ntpHost = string(body)
+		case recPort:
+			if len(body) != 2 {
+				return ErrBadKEResponse
+			}
+			ntpPort = strconv.Itoa(int(binary.BigEndian.Uint16(body)))
+		default:
+			if critical {
+				return fmt.Errorf("%w: unknown critical record %d", ErrBadKEResponse, typ)
+			}
+		}
+	}
+	return fmt.Errorf("%w: too many records", ErrBadKEResponse)
+}
+
+// exportKey derives the client-to-server (direction 0) or server-to-client (direction 1) key
+func exportKey(state tls.ConnectionState, direction byte) ([]byte, error) {
+	exporterContext := []byte{0, protocolNTPv4, 0, aeadAESSIVCMAC256, direction}
+	return state.ExportKeyingMaterial(exporterLabel, exporterContext, keyLen)
+}
+
+// Cookies returns the number of unused cookies
+func (s *Session) Cookies() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cookies)
+}
+
+func (s *Session) popCookie() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cookies) == 0 {
+		return nil, ErrNoCookies
+	}
+	cookie := s.cookies[0]
+	s.cookies = s.cookies[1:]
+	return cookie, nil
+}
+
+func (s *Session) pushCookies(cookies [][]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cookies = append(s.cookies, cookies...)
+}
@@ -0,0 +1,196 @@
+package nts
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/beevik/ntp"
+)
+
+// Типы полей расширения NTP (RFC 8915, раздел 5.7)
+const (
+	extUniqueID          = 0x0104
+	extCookie            = 0x0204
+	extCookiePlaceholder = 0x0304
+	extAuthenticator     = 0x0404
+
+	headerLen   = 48
+	uniqueIDLen = 32
+	nonceLen    = 16
+	wantCookies = 8
+	minExtLen   = 16
+)
+
+var (
+	// ErrNotAuthenticated is returned when the response has no valid authenticator
+	ErrNotAuthenticated = errors.New("nts: response is not authenticated")
+	// ErrNAK is returned when the server could not use the cookie, a new key exchange is required
+	ErrNAK = errors.New("nts: server sent NTS negative acknowledgment")
+)
+
+type extField struct {
+	typ   uint16
+	start int // смещение поля в пакете
+	body  []byte
+}
+
+// appendExt appends an extension field padded to a multiple of 4 bytes
+func appendExt(buf []byte, typ uint16, body []byte) []byte {
+	length := max((4+len(body)+3)&^3, minExtLen)
+	buf = binary.BigEndian.AppendUint16(buf, typ)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	buf = append(buf, body...)
+	return append(buf, make([]byte, length-4-len(body))...)
+}
+
+// parseExts splits data into extension fields, start offsets are counted from base
+func parseExts(data []byte, base int) ([]extField, error) {
+	var fields []extField
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 4 {
+			return nil, errors.New("nts: truncated extension field")
+		}
+		typ := binary.BigEndian.Uint16(data[pos:])
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 4 || length%4 != 0 || pos+length > len(data) {
+			return nil, fmt.Errorf("nts: invalid extension field length %d", length)
+		}
+		fields = append(fields, extField{typ, base + pos, data[pos+4 : pos+length]})
+		pos += length
+	}
+	return fields, nil
+}
+
+// appendAuthenticator seals plaintext with the associated data buf and appends the authenticator field
+func appendAuthenticator(buf []byte, key, plaintext []byte) ([]byte, error) {
+	aead, err := newSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := aead.Seal(nil, nonce, plaintext, buf)
+
+	body := binary.BigEndian.AppendUint16(nil, nonceLen)
+	body = binary.BigEndian.AppendUint16(body, uint16(len(ciphertext)))
+	body = append(body, nonce...)
+	body = append(body, ciphertext...)
+	return appendExt(buf, extAuthenticator, body), nil
+}
+
+// openAuthenticator verifies the authenticator field over packet[:field.start] and returns the plaintext
+func openAuthenticator(packet []byte, field extField, key []byte) ([]byte, error) {
+	body := field.body
+	if len(body) < 4 {
+		return nil, ErrNotAuthenticated
+	}
+	nLen := int(binary.BigEndian.Uint16(body))
+	cLen := int(binary.BigEndian.Uint16(body[2:]))
+	nPadded := (nLen + 3) &^ 3
+	if 4+nPadded+cLen > len(body) {
+		return nil, ErrNotAuthenticated
+	}
+	nonce := body[4 : 4+nLen]
+	ciphertext := body[4+nPadded : 4+nPadded+cLen]
+
+	aead, err := newSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, packet[:field.start])
+	if err != nil {
+		return nil, ErrNotAuthenticated
+	}
+	return plaintext, nil
+}
+
+// queryExtension authenticates a single NTP query
+type queryExtension struct {
+	session  *Session
+	cookie   []byte
+	uniqueID []byte
+}
+
+// Extension returns an ntp.Extension that protects one query with NTS. Every call consumes
+// a cookie, fresh cookies from the server response are added back to the session.
+func (s *Session) Extension() (ntp.Extension, error) {
+	cookie, err := s.popCookie()
+	if err != nil {
+		return nil, err
+	}
+	uid := make([]byte, uniqueIDLen)
+	if _, err := rand.Read(uid); err != nil {
+		return nil, err
+	}
+	return &queryExtension{session: s, cookie: cookie, uniqueID: uid}, nil
+}
+
+// ProcessQuery appends the unique identifier, cookie, placeholders and authenticator
+func (q *queryExtension) ProcessQuery(buf *bytes.Buffer) error {
+	packet := appendExt(buf.Bytes(), extUniqueID, q.uniqueID)
+	packet = appendExt(packet, extCookie, q.cookie)
+
+	// Плейсхолдеры просят сервер вернуть дополнительные куки до полного набора
+	placeholder := make([]byte, len(q.cookie))
+	for range wantCookies - 1 - q.session.Cookies() {
+		packet = appendExt(packet, extCookiePlaceholder, placeholder)
+	}
+
+	packet, err := appendAuthenticator(packet, q.session.c2s, nil)
+	if err != nil {
+		return err
+	}
+	buf.Reset()
+	buf.Write(packet)
+	return nil
+}
+
+// ProcessResponse checks the unique identifier and authenticator and stores new cookies
+func (q *queryExtension) ProcessResponse(buf []byte) error {
+	if len(buf) < headerLen {
+		return ErrNotAuthenticated
+	}
+	fields, err := parseExts(buf[headerLen:], headerLen)
+	if err != nil {
+		return err
+	}
+
+	uidOK := false
+	for _, f := range fields {
+		switch f.typ {
+		case extUniqueID:
+			uidOK = bytes.Equal(f.body[:min(len(f.body), uniqueIDLen)], q.uniqueID)
+		case extAuthenticator:
+			if !uidOK {
+				return ErrNotAuthenticated
+			}
+			plaintext, err := openAuthenticator(buf, f, q.session.s2c)
+			if err != nil {
+				return err
+			}
+			inner, err := parseExts(plaintext, 0)
+			if err != nil {
+				return err
+			}
+			var cookies [][]byte
+			for _, c := range inner {
+				if c.typ == extCookie {
+					cookies = append(cookies, c.body)
+				}
+			}
+			q.session.pushCookies(cookies)
+			// Поля после аутентификатора не защищены и игнорируются
+			return nil
+		}
+	}
+
+	if uidOK && buf[1] == 0 && string(buf[12:16]) == "NTSN" {
+		return ErrNAK
+	}
+	return ErrNotAuthenticated
+}
@@ -0,0 +1,204 @@
+package nts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// selfSigned returns a server config with a self-signed certificate for 127.0.0.1
+// and a client config trusting it
+func selfSigned(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nts stand-in"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool}
+	return server, client
+}
+
+func toNtpTime(t time.Time) uint64 {
+	nsec := uint64(t.Sub(time.Unix(-2208988800, 0)))
+	return nsec/1e9<<32 | (nsec%1e9)<<32/1e9
+}
+
+func plainRespond(req []byte, recvTime time.Time) ([]byte, bool) {
+	resp := make([]byte, headerLen)
+	resp[0] = 4<<3 | 4
+	resp[1] = 1
+	copy(resp[12:], "LOCL")
+	binary.BigEndian.PutUint64(resp[16:], toNtpTime(recvTime))
+	copy(resp[24:32], req[40:48])
+	binary.BigEndian.PutUint64(resp[32:], toNtpTime(recvTime))
+	binary.BigEndian.PutUint64(resp[40:], toNtpTime(time.Now()))
+	return resp, true
+}
+
+// startStandIn starts NTS-KE and NTP servers and returns the KE address
+func startStandIn(t *testing.T, serverCfg *tls.Config) (*Server, string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := NewServer(serverCfg, plainRespond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.NTPAddr = udp.LocalAddr().String()
+	go func() { _ = srv.ServeNTP(ctx, udp) }()
+	go func() { _ = srv.ServeKE(ctx, tcp) }()
+	return srv, tcp.Addr().String()
+}
+
+func query(t *testing.T, s *Session) (*ntp.Response, error) {
+	t.Helper()
+	ext, err := s.Extension()
+	if err != nil {
+		return nil, err
+	}
+	return ntp.QueryWithOptions(s.Server, ntp.QueryOptions{
+		Timeout:    time.Second,
+		Extensions: []ntp.Extension{ext},
+	})
+}
+
+func TestAuthenticatedQuery(t *testing.T) {
+	serverCfg, clientCfg := selfSigned(t)
+	srv, keAddr := startStandIn(t, serverCfg)
+
+	s, err := KeyExchange(context.Background(), keAddr, clientCfg)
+	if err != nil {
+		t.Fatalf("key exchange: %v", err)
+	}
+	if s.Server != srv.NTPAddr {
+		t.Errorf("expected NTP server %s, got %s", srv.NTPAddr, s.Server)
+	}
+	if s.Cookies() != wantCookies {
+		t.Errorf("expected %d cookies, got %d", wantCookies, s.Cookies())
+	}
+
+	// Куки должны пополняться после каждого запроса
+	for i := range 2 * wantCookies {
+		resp, err := query(t, s)
+		if err != nil {
+			t.Fatalf("query %d: %v", i, err)
+		}
+		if err := resp.Validate(); err != nil {
+			t.Fatalf("query %d: invalid response: %v", i, err)
+		}
+		if s.Cookies() != wantCookies {
+			t.Fatalf("query %d: expected %d cookies, got %d", i, wantCookies, s.Cookies())
+		}
+	}
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	serverCfg, _ := selfSigned(t)
+	_, keAddr := startStandIn(t, serverCfg)
+
+	_, err := KeyExchange(context.Background(), keAddr, nil)
+	var certErr *tls.CertificateVerificationError
+	if !errors.As(err, &certErr) {
+		t.Errorf("expected certificate verification error, got %v", err)
+	}
+}
+
+func TestForgedResponse(t *testing.T) {
+	serverCfg, clientCfg := selfSigned(t)
+	_, keAddr := startStandIn(t, serverCfg)
+	s, err := KeyExchange(context.Background(), keAddr, clientCfg)
+	if err != nil {
+		t.Fatalf("key exchange: %v", err)
+	}
+
+	// Сервер без ключей сессии не может подписать ответ
+	ext, err := s.Extension()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, _ := plainRespond(make([]byte, headerLen), time.Now())
+	forged = appendExt(forged, extUniqueID, ext.(*queryExtension).uniqueID)
+	forged, err = appendAuthenticator(forged, make([]byte, keyLen), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ext.ProcessResponse(forged); !errors.Is(err, ErrNotAuthenticated) {
+		t.Errorf("expected ErrNotAuthenticated, got %v", err)
+	}
+
+	// Ответ без полей NTS
+	plain, _ := plainRespond(make([]byte, headerLen), time.Now())
+	if err := ext.ProcessResponse(plain); !errors.Is(err, ErrNotAuthenticated) {
+		t.Errorf("expected ErrNotAuthenticated for plain response, got %v", err)
+	}
+}
+
+func TestNAK(t *testing.T) {
+	serverCfg, clientCfg := selfSigned(t)
+	_, keAddr := startStandIn(t, serverCfg)
+	s, err := KeyExchange(context.Background(), keAddr, clientCfg)
+	if err != nil {
+		t.Fatalf("key exchange: %v", err)
+	}
+
+	// Куки другого сервера не расшифровываются мастер-ключом
+	s.mu.Lock()
+	for i := range s.cookies {
+		s.cookies[i] = make([]byte, len(s.cookies[i]))
+	}
+	s.mu.Unlock()
+
+	if _, err := query(t, s); !errors.Is(err, ErrNAK) {
+		t.Errorf("expected ErrNAK, got %v", err)
+	}
+}
+
+func TestNoCookies(t *testing.T) {
+	s := &Session{}
+	if _, err := s.Extension(); !errors.Is(err, ErrNoCookies) {
+		t.Errorf("expected ErrNoCookies, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package nts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RespondFunc builds the plain 48-byte NTP answer to req, false means the request is ignored
+type RespondFunc func(req []byte, recvTime time.Time) ([]byte, bool)
+
+// Server is a minimal NTS-KE and NTS-protected NTP server. It is intended as a local
+// stand-in for tests: cookies are encrypted with a random master key living in memory.
+type Server struct {
+	// NTPAddr is the host:port of the NTP server advertised in the key exchange, empty means
+	// the client uses the KE host and port 123
+	NTPAddr string
+
+	tlsConfig *tls.Config
+	respond   RespondFunc
+	masterKey []byte
+}
+
+// NewServer creates a server using tlsConfig for NTS-KE and respond for the time part of answers
+func NewServer(tlsConfig *tls.Config, respond RespondFunc) (*Server, error) {
+	cfg := tlsConfig.Clone()
+	cfg.MinVersion = tls.VersionTLS13
+	cfg.NextProtos = []string{alpnProtocol}
+
+	key := make([]byte, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Server{tlsConfig: cfg, respond: respond, masterKey: key}, nil
+}
+
+// ServeKE accepts key exchange connections on ln until ctx is done
+func (s *Server) ServeKE(ctx context.Context, ln net.Listener) error {
+	tlsLn := tls.NewListener(ln, s.tlsConfig)
+	defer tlsLn.Close()
+	go func() {
+		<-ctx.Done()
+		tlsLn.Close()
+	}()
+
+	for {
+		conn, err := tlsLn.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handleKE(conn.(*tls.Conn))
+	}
+}
+
+func (s *Server) handleKE(conn *tls.Conn) {
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return
+	}
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+
+	if err := readKERequest(conn); err != nil {
+		_, _ = conn.Write(appendRecord(appendRecord(nil, recError, true, []byte{0, 1}), recEndOfMessage, true, nil))
+		return
+	}
+
+	state := conn.ConnectionState()
+	c2s, err := exportKey(state, 0)
+	if err != nil {
+		return
+	}
+	s2c, err := exportKey(state, 1)
+	if err != nil {
+		return
+	}
+
+	resp := appendRecord(nil, recNextProtocol, true, binary.BigEndian.AppendUint16(nil, protocolNTPv4))
+	resp = appendRecord(resp, recAEAD, true, binary.BigEndian.AppendUint16(nil, aeadAESSIVCMAC256))
+	for range wantCookies {
+		cookie, err := s.makeCookie(c2s, s2c)
+		if err != nil {
+			return
+		}
+		resp = appendRecord(resp, recNewCookie, false, cookie)
+	}
+	if s.NTPAddr != "" {
+		host, port, err := net.SplitHostPort(s.NTPAddr)
+		if err != nil {
+			return
+		}
+		portNum, err := strconv.Atoi(port)
+		if err != nil {
+			return
+		}
+		resp = appendRecord(resp, recServer, true, []byte(host))
+		resp = appendRecord(resp, recPort, true, binary.BigEndian.AppendUint16(nil, uint16(portNum)))
+	}
+	resp = appendRecord(resp, recEndOfMessage, true, nil)
+	_, _ = conn.Write(resp)
+}
+
+// readKERequest checks that the client asks for NTPv4 with AES-SIV-CMAC-256
+func readKERequest(r io.Reader) error {
+	var protoOK, aeadOK bool
+	hdr := make([]byte, 4)
+	for range maxRecordCount {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return err
+		}
+		critical := binary.BigEndian.Uint16(hdr)&criticalBit != 0
+		typ := binary.BigEndian.Uint16(hdr) &^ criticalBit
+		body := make([]byte, binary.BigEndian.Uint16(hdr[2:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+
+		switch typ {
+		case recEndOfMessage:
+			if !protoOK || !aeadOK {
+				return ErrBadKEResponse
+			}
+			return nil
+		case recNextProtocol:
+			protoOK = containsUint16(body, protocolNTPv4)
+		case recAEAD:
+			aeadOK = containsUint16(body, aeadAESSIVCMAC256)
+		default:
+			if critical {
+				return ErrBadKEResponse
+			}
+		}
+	}
+	return ErrBadKEResponse
+}
+
+func containsUint16(body []byte, v uint16) bool {
+	for i := 0; i+1 < len(body); i += 2 {
+		if binary.BigEndian.Uint16(body[i:]) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// makeCookie encrypts the session keys with the master key: nonce || SIV(c2s || s2c)
+func (s *Server) makeCookie(c2s, s2c []byte) ([]byte, error) {
+	aead, err := newSIV(s.masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceLen)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, append(append([]byte(nil), c2s...), s2c...), nil), nil
+}
+
+func (s *Server) openCookie(cookie []byte) (c2s, s2c []byte, err error) {
+	if len(cookie) < nonceLen {
+		return nil, nil, ErrNotAuthenticated
+	}
+	aead, err := newSIV(s.masterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := aead.Open(nil, cookie[:nonceLen], cookie[nonceLen:], nil)
+	if err != nil || len(keys) != 2*keyLen {
+		return nil, nil, ErrNotAuthenticated
+	}
+	return keys[:keyLen], keys[keyLen:], nil
+}
+
+// ServeNTP answers NTP requests on conn until ctx is done. Requests without NTS fields
+// get plain answers, requests with an unusable cookie get an NTS NAK.
+func (s *Server) ServeNTP(ctx context.Context, conn net.PacketConn) error {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		recvTime := time.Now()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+		if resp := s.handleNTP(buf[:n], recvTime); resp != nil {
+			_, _ = conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *Server) handleNTP(req []byte, recvTime time.Time) []byte {
+	if len(req) < headerLen {
+		return nil
+	}
+	fields, err := parseExts(req[headerLen:], headerLen)
+	if err != nil {
+		return nil
+	}
+
+	var uid, cookie []byte
+	var auth *extField
+	placeholders := 0
+	for i, f := range fields {
+		switch f.typ {
+		case extUniqueID:
+			uid = f.body
+		case extCookie:
+			cookie = f.body
+		case extCookiePlaceholder:
+			placeholders++
+		case extAuthenticator:
+			auth = &fields[i]
+		}
+	}
+
+	resp, ok := s.respond(req[:headerLen], recvTime)
+	if !ok || len(resp) < headerLen {
+		return nil
+	}
+	resp = resp[:headerLen]
+	if uid == nil && cookie == nil && auth == nil {
+		return resp
+	}
+	if uid == nil || auth == nil {
+		return nil
+	}
+
+	resp = appendExt(resp, extUniqueID, uid)
+	c2s, s2c, err := s.openCookie(cookie)
+	if err == nil {
+		_, err = openAuthenticator(req, *auth, c2s)
+	}
+	if err != nil {
+		// NTS NAK: kiss-of-death с кодом NTSN и эхом идентификатора
+		resp[1] = 0
+		copy(resp[12:16], "NTSN")
+		return resp
+	}
+
+	var plaintext []byte
+	for range 1 + placeholders {
+		newCookie, err := s.makeCookie(c2s, s2c)
+		if err != nil {
+			return nil
+		}
+		plaintext = appendExt(plaintext, extCookie, newCookie)
+	}
+	resp, err = appendAuthenticator(resp, s2c, plaintext)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
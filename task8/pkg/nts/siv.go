@@ -0,0 +1,166 @@
+package nts
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// aeadAESSIVCMAC256 is the AEAD algorithm identifier from the IANA registry (RFC 5297)
+const aeadAESSIVCMAC256 = 15
+
+var errOpen = errors.New("nts: message authentication failed")
+
+// siv implements AEAD_AES_SIV_CMAC_256 (RFC 5297) as cipher.AEAD.
+// The nonce is passed to S2V as the last associated data component.
+type siv struct {
+	mac cipher.Block // K1 для S2V
+	ctr cipher.Block // K2 для шифрования
+}
+
+func newSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("nts: AES-SIV-CMAC-256 requires a 32 byte key")
+	}
+	mac, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, err
+	}
+	return &siv{mac, ctr}, nil
+}
+
+func (s *siv) NonceSize() int { return 16 }
+func (s *siv) Overhead() int  { return aes.BlockSize }
+
+func (s *siv) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	v := s.s2v(additionalData, nonce, plaintext)
+	ret, out := sliceForAppend(dst, aes.BlockSize+len(plaintext))
+	copy(out, v[:])
+	s.crypt(out[aes.BlockSize:], v, plaintext)
+	return ret
+}
+
+func (s *siv) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errOpen
+	}
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext)
+	ret, out := sliceForAppend(dst, len(ciphertext)-aes.BlockSize)
+	s.crypt(out, v, ciphertext[aes.BlockSize:])
+
+	expected := s.s2v(additionalData, nonce, out)
+	if subtle.ConstantTimeCompare(expected[:], v[:]) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// crypt applies AES-CTR keyed with K2, the counter is V with bits 31 and 63 cleared
+func (s *siv) crypt(dst []byte, v [aes.BlockSize]byte, src []byte) {
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(s.ctr, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v computes the synthetic IV over the associated data, nonce and plaintext
+func (s *siv) s2v(ad, nonce, plaintext []byte) [aes.BlockSize]byte {
+	var zero [aes.BlockSize]byte
+	d := cmac(s.mac, zero[:])
+	for _, component := range [][]byte{ad, nonce} {
+		if component == nil {
+			continue
+		}
+		d = dbl(d)
+		xorBlock(&d, cmac(s.mac, component))
+	}
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte(nil), plaintext...)
+		tail := t[len(t)-aes.BlockSize:]
+		for i := range tail {
+			tail[i] ^= d[i]
+		}
+	} else {
+		d = dbl(d)
+		var padded [aes.BlockSize]byte
+		copy(padded[:], plaintext)
+		padded[len(plaintext)] = 0x80
+		xorBlock(&d, padded)
+		t = d[:]
+	}
+	return cmac(s.mac, t)
+}
+
+// cmac computes AES-CMAC (RFC 4493)
+func cmac(b cipher.Block, msg []byte) [aes.BlockSize]byte {
+	var zero, l [aes.BlockSize]byte
+	b.Encrypt(l[:], zero[:])
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n > 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	var last [aes.BlockSize]byte
+	rest := msg[(n-1)*aes.BlockSize:]
+	copy(last[:], rest)
+	if complete {
+		xorBlock(&last, k1)
+	} else {
+		last[len(rest)] = 0x80
+		xorBlock(&last, k2)
+	}
+
+	var x [aes.BlockSize]byte
+	for i := 0; i < n-1; i++ {
+		var block [aes.BlockSize]byte
+		copy(block[:], msg[i*aes.BlockSize:])
+		xorBlock(&x, block)
+		b.Encrypt(x[:], x[:])
+	}
+	xorBlock(&x, last)
+	b.Encrypt(x[:], x[:])
+	return x
+}
+
+// dbl multiplies the block by x in GF(2^128)
+func dbl(in [aes.BlockSize]byte) [aes.BlockSize]byte {
+	var out [aes.BlockSize]byte
+	var carry byte
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	if carry != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+func xorBlock(dst *[aes.BlockSize]byte, src [aes.BlockSize]byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,64 @@
+package nts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Тестовый вектор из RFC 5297, приложение A.1 (без nonce)
+func TestSIVVector(t *testing.T) {
+	key := mustHex(t, "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad := mustHex(t, "101112131415161718191a1b1c1d1e1f2021222324252627")
+	plaintext := mustHex(t, "112233445566778899aabbccddee")
+	expected := mustHex(t, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	aead, err := newSIV(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := aead.Seal(nil, nil, plaintext, ad)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("wrong ciphertext\nexpected: %x\nactual: %x", expected, out)
+	}
+
+	opened, err := aead.Open(nil, nil, out, ad)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("open failed: %v %x", err, opened)
+	}
+}
+
+func TestSIVRoundTrip(t *testing.T) {
+	aead, err := newSIV(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := bytes.Repeat([]byte{1}, 16)
+	ad := []byte("header")
+
+	for _, size := range []int{0, 5, 16, 40} {
+		plaintext := bytes.Repeat([]byte{0xab}, size)
+		sealed := aead.Seal(nil, nonce, plaintext, ad)
+		opened, err := aead.Open(nil, nonce, sealed, ad)
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("size %d: open failed: %v", size, err)
+		}
+
+		sealed[len(sealed)-1] ^= 1
+		if _, err := aead.Open(nil, nonce, sealed, ad); err == nil {
+			t.Errorf("size %d: tampered message accepted", size)
+		}
+		if _, err := aead.Open(nil, nonce, aead.Seal(nil, nonce, plaintext, ad), []byte("other")); err == nil {
+			t.Errorf("size %d: wrong associated data accepted", size)
+		}
+	}
+}
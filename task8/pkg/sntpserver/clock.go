@@ -0,0 +1,142 @@
+package sntpserver
+
+import (
+	"context"
+	"crypto/md5"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/ntpprinttime"
+)
+
+// ClockStatus describes the synchronization state reported in responses
+type ClockStatus struct {
+	Synchronized   bool
+	Stratum        uint8
+	ReferenceID    [4]byte // для stratum 1 - ASCII идентификатор источника, иначе IPv4 адрес или хеш IPv6
+	ReferenceTime  time.Time
+	RootDelay      time.Duration
+	RootDispersion time.Duration
+}
+
+// Clock is the time source of the server
+type Clock interface {
+	Now() time.Time
+	Status() ClockStatus
+}
+
+// LocalClock serves the local system time as a primary (stratum 1) source
+type LocalClock struct{}
+
+// Now returns the local time
+func (LocalClock) Now() time.Time { return time.Now() }
+
+// Status reports the local clock as a synchronized primary source "LOCL"
+func (LocalClock) Status() ClockStatus {
+	return ClockStatus{
+		Synchronized:  true,
+		Stratum:       1,
+		ReferenceID:   [4]byte{'L', 'O', 'C', 'L'},
+		ReferenceTime: time.Now(),
+	}
+}
+
+// UpstreamClock is the local clock corrected by the offset agreed by upstream servers
+type UpstreamClock struct {
+	opts     ntpprinttime.ConsensusOptions
+	interval time.Duration
+
+	mu     sync.RWMutex
+	offset time.Duration
+	status ClockStatus
+}
+
+// NewUpstreamClock creates a clock disciplined by the given servers, call Run to start syncing
+func NewUpstreamClock(opts ntpprinttime.ConsensusOptions, interval time.Duration) *UpstreamClock {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &UpstreamClock{opts: opts, interval: interval}
+}
+
+// Now returns the corrected time
+func (c *UpstreamClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Now().Add(c.offset)
+}
+
+// Status returns the state of the last successful synchronization.
+// A clock whose source is at stratum 15 is reported as unsynchronized (stratum 16).
+func (c *UpstreamClock) Status() ClockStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.status
+}
+
+// Sync queries upstream servers once and updates the offset
+func (c *UpstreamClock) Sync(ctx context.Context) error {
+	cons, err := ntpprinttime.QueryConsensus(ctx, c.opts)
+	if err != nil {
+		return err
+	}
+
+	// Источником считается truechimer с наименьшим stratum
+	var source *ntpprinttime.Sample
+	for i := range cons.Samples {
+		s := &cons.Samples[i]
+		if s.Truechimer && (source == nil || s.Stratum < source.Stratum) {
+			source = s
+		}
+	}
+	if source == nil {
+		return ntpprinttime.ErrNoConsensus
+	}
+
+	status := ClockStatus{
+		Stratum:        source.Stratum + 1,
+		ReferenceID:    referenceID(source.Address),
+		ReferenceTime:  cons.Time,
+		RootDelay:      source.RootDelay + source.RTT,
+		RootDispersion: cons.ErrorBound,
+	}
+	status.Synchronized = status.Stratum < stratumUnsynced
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = cons.Offset
+	c.status = status
+	return nil
+}
+
+// referenceID returns the IPv4 address of the upstream server or, for IPv6,
+// the first four octets of its MD5 hash (RFC 5905, section 7.3)
+func referenceID(ip net.IP) [4]byte {
+	var id [4]byte
+	if ip4 := ip.To4(); ip4 != nil {
+		copy(id[:], ip4)
+	} else if ip != nil {
+		sum := md5.Sum(ip.To16())
+		copy(id[:], sum[:4])
+	}
+	return id
+}
+
+// Run syncs immediately and then on every interval until ctx is done.
+// Sync errors are passed to onError if it is not nil, the previous offset is kept.
+func (c *UpstreamClock) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Sync(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
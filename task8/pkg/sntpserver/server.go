@@ -0,0 +1,137 @@
+// Package sntpserver implements a minimal SNTPv4 server (RFC 4330)
+package sntpserver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	packetLen      = 48
+	ntpEpochOffset = 2208988800 // секунды между 1900 и 1970 годами
+
+	modeClient = 3
+	modeServer = 4
+
+	leapNotInSync     = 3
+	stratumUnsynced   = 16
+	defaultListenAddr = ":123"
+)
+
+// Server answers SNTP client requests with the time of its Clock
+type Server struct {
+	Addr  string // адрес для ListenAndServe, по умолчанию ":123"
+	Clock Clock  // источник времени, по умолчанию LocalClock
+}
+
+// NewServer creates a server listening on addr and serving the time of clock
+func NewServer(addr string, clock Clock) *Server {
+	return &Server{Addr: addr, Clock: clock}
+}
+
+// ListenAndServe listens on the UDP address s.Addr and serves requests until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	addr := s.Addr
+	if addr == "" {
+		addr = defaultListenAddr
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, conn)
+}
+
+// Serve answers requests received on conn until ctx is done. Conn is closed on return.
+func (s *Server) Serve(ctx context.Context, conn net.PacketConn) error {
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close() // прерываем блокирующий ReadFrom
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		recvTime := s.clock().Now()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			continue
+		}
+
+		resp, ok := s.respond(buf[:n], recvTime)
+		if !ok {
+			continue
+		}
+		// Ошибка отправки одному клиенту не должна останавливать сервер
+		_, _ = conn.WriteTo(resp, addr)
+	}
+}
+
+func (s *Server) clock() Clock {
+	if s.Clock == nil {
+		return LocalClock{}
+	}
+	return s.Clock
+}
+
+// respond builds the answer to req, requests that are not client mode are ignored
+func (s *Server) respond(req []byte, recvTime time.Time) ([]byte, bool) {
+	if len(req) < packetLen {
+		return nil, false
+	}
+	version := (req[0] >> 3) & 0x07
+	mode := req[0] & 0x07
+	if mode != modeClient || version < 1 || version > 4 {
+		return nil, false
+	}
+
+	clock := s.clock()
+	status := clock.Status()
+	resp := make([]byte, packetLen)
+
+	leap, stratum := uint8(0), status.Stratum
+	if !status.Synchronized {
+		leap, stratum = leapNotInSync, stratumUnsynced
+	}
+	resp[0] = leap<<6 | version<<3 | modeServer
+	resp[1] = stratum
+	resp[2] = req[2] // poll копируется из запроса
+	resp[3] = 0xec   // precision 2^-20 ≈ 1 мкс
+	binary.BigEndian.PutUint32(resp[4:], toNtpShort(status.RootDelay))
+	binary.BigEndian.PutUint32(resp[8:], toNtpShort(status.RootDispersion))
+	copy(resp[12:16], status.ReferenceID[:])
+	if !status.ReferenceTime.IsZero() {
+		binary.BigEndian.PutUint64(resp[16:], toNtpTime(status.ReferenceTime))
+	}
+	copy(resp[24:32], req[40:48]) // origin = transmit time клиента
+	binary.BigEndian.PutUint64(resp[32:], toNtpTime(recvTime))
+	binary.BigEndian.PutUint64(resp[40:], toNtpTime(clock.Now()))
+	return resp, true
+}
+
+// toNtpTime converts t to the 64-bit NTP timestamp format
+func toNtpTime(t time.Time) uint64 {
+	nsec := uint64(t.Sub(time.Unix(-ntpEpochOffset, 0)))
+	sec := nsec / 1e9
+	frac := (nsec % 1e9) << 32 / 1e9
+	return sec<<32 | frac
+}
+
+// toNtpShort converts d to the 32-bit NTP short format (16.16 fixed point seconds)
+func toNtpShort(d time.Duration) uint32 {
+	if d < 0 {
+		return 0
+	}
+	sec := uint64(d / time.Second)
+	frac := uint64(d%time.Second) << 16 / 1e9
+	return uint32(sec<<16 | frac)
+}
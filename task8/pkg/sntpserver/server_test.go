@@ -0,0 +1,185 @@
+package sntpserver
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/pozedorum/WB_project_2.8/task8/pkg/ntpprinttime"
+)
+
+type shiftedClock struct {
+	shift  time.Duration
+	status ClockStatus
+}
+
+func (c shiftedClock) Now() time.Time      { return time.Now().Add(c.shift) }
+func (c shiftedClock) Status() ClockStatus { return c.status }
+
+func startServer(t *testing.T, clock Clock) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := NewServer("", clock).Serve(ctx, conn); err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return conn.LocalAddr().String()
+}
+
+func TestLocalClockRoundTrip(t *testing.T) {
+	addr := startServer(t, LocalClock{})
+
+	resp, err := ntp.QueryWithOptions(addr, ntp.QueryOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if err := resp.Validate(); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.Stratum != 1 || resp.ReferenceString() != ".LOCL." {
+		t.Errorf("wrong stratum or reference: %d %s", resp.Stratum, resp.ReferenceString())
+	}
+	if resp.ClockOffset < -10*time.Millisecond || resp.ClockOffset > 10*time.Millisecond {
+		t.Errorf("offset too large: %v", resp.ClockOffset)
+	}
+}
+
+func TestShiftedClock(t *testing.T) {
+	addr := startServer(t, shiftedClock{
+		shift: time.Hour,
+		status: ClockStatus{
+			Synchronized:   true,
+			Stratum:        3,
+			ReferenceID:    [4]byte{10, 0, 0, 1},
+			ReferenceTime:  time.Now().Add(time.Hour),
+			RootDispersion: 5 * time.Millisecond,
+		},
+	})
+
+	resp, err := ntp.QueryWithOptions(addr, ntp.QueryOptions{Timeout: time.Second, Version: 3})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if err := resp.Validate(); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if diff := resp.ClockOffset - time.Hour; diff < -10*time.Millisecond || diff > 10*time.Millisecond {
+		t.Errorf("expected offset of 1h, got %v", resp.ClockOffset)
+	}
+	if resp.Version != 3 || resp.Stratum != 3 || resp.ReferenceString() != "10.0.0.1" {
+		t.Errorf("wrong header: version %d, stratum %d, reference %s", resp.Version, resp.Stratum, resp.ReferenceString())
+	}
+	if resp.RootDispersion < 4*time.Millisecond || resp.RootDispersion > 6*time.Millisecond {
+		t.Errorf("wrong root dispersion: %v", resp.RootDispersion)
+	}
+}
+
+func TestUnsynchronizedClock(t *testing.T) {
+	addr := startServer(t, NewUpstreamClock(ntpprinttime.ConsensusOptions{}, time.Minute))
+
+	resp, err := ntp.QueryWithOptions(addr, ntp.QueryOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if err := resp.Validate(); !errors.Is(err, ntp.ErrInvalidStratum) {
+		t.Errorf("expected ErrInvalidStratum, got %v", err)
+	}
+	if resp.Leap != ntp.LeapNotInSync {
+		t.Errorf("expected leap indicator %d, got %d", ntp.LeapNotInSync, resp.Leap)
+	}
+}
+
+func TestUpstreamClock(t *testing.T) {
+	upstream := []string{
+		startServer(t, LocalClock{}),
+		startServer(t, LocalClock{}),
+		startServer(t, shiftedClock{shift: time.Hour, status: LocalClock{}.Status()}),
+	}
+	clock := NewUpstreamClock(ntpprinttime.ConsensusOptions{Servers: upstream, Timeout: time.Second}, time.Minute)
+	if err := clock.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	addr := startServer(t, clock)
+
+	c, err := ntpprinttime.QueryConsensus(context.Background(), ntpprinttime.ConsensusOptions{
+		Servers: []string{addr},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if c.Offset < -10*time.Millisecond || c.Offset > 10*time.Millisecond {
+		t.Errorf("offset too large: %v", c.Offset)
+	}
+	if c.Samples[0].Stratum != 2 {
+		t.Errorf("expected stratum 2, got %d", c.Samples[0].Stratum)
+	}
+
+	status := clock.Status()
+	if status.ReferenceID != [4]byte{127, 0, 0, 1} {
+		t.Errorf("expected upstream address as reference, got %v", status.ReferenceID)
+	}
+	if status.RootDelay <= 0 {
+		t.Errorf("expected positive root delay, got %v", status.RootDelay)
+	}
+}
+
+func TestUpstreamClockMaxStratum(t *testing.T) {
+	upstream := startServer(t, shiftedClock{status: ClockStatus{
+		Synchronized:  true,
+		Stratum:       15,
+		ReferenceID:   [4]byte{10, 0, 0, 1},
+		ReferenceTime: time.Now(),
+	}})
+	clock := NewUpstreamClock(ntpprinttime.ConsensusOptions{Servers: []string{upstream}, Timeout: time.Second}, time.Minute)
+	if err := clock.Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	addr := startServer(t, clock)
+
+	resp, err := ntp.QueryWithOptions(addr, ntp.QueryOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if resp.Stratum != 16 || resp.Leap != ntp.LeapNotInSync {
+		t.Errorf("expected unsynchronized stratum 16, got stratum %d, leap %d", resp.Stratum, resp.Leap)
+	}
+}
+
+func TestReferenceID(t *testing.T) {
+	if id := referenceID(net.ParseIP("192.0.2.7")); id != [4]byte{192, 0, 2, 7} {
+		t.Errorf("wrong IPv4 reference: %v", id)
+	}
+	ip := net.ParseIP("2001:db8::1")
+	sum := md5.Sum(ip)
+	if id := referenceID(ip); id != [4]byte(sum[:4]) {
+		t.Errorf("wrong IPv6 reference: %v", id)
+	}
+}
+
+func TestRespondIgnoresNonClient(t *testing.T) {
+	s := NewServer("", LocalClock{})
+	req := make([]byte, packetLen)
+	req[0] = 4<<3 | modeServer
+	if _, ok := s.respond(req, time.Now()); ok {
+		t.Error("server mode request must be ignored")
+	}
+	if _, ok := s.respond(req[:10], time.Now()); ok {
+		t.Error("short request must be ignored")
+	}
+}
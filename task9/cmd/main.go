@@ -1,19 +1,135 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/pozedorum/WB_project_2.8/task9"
 )
 
+const maxRecordSize = 64 * 1024 * 1024
+
+type config struct {
+	pack bool
+	null bool
+	opts task9.Options
+}
+
 func main() {
-	var input string
-	fmt.Scan(&input)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run processes every record of the inputs and returns the exit status:
+// 0 - all records succeeded, 1 - some records failed, 2 - usage or read error
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("unpack", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cfg config
+	fs.BoolVar(&cfg.pack, "pack", false, "pack records instead of unpacking")
+	fs.BoolVar(&cfg.null, "null", false, "records are separated by NUL instead of newline")
+	fs.BoolVar(&cfg.opts.MultiDigit, "multi-digit", false, "counts may have several digits")
+	fs.BoolVar(&cfg.opts.Graphemes, "graphemes", false, "repeat grapheme clusters instead of runes")
+	fs.Float64Var(&cfg.opts.MaxExpansion, "max-expansion", task9.DefaultMaxExpansion, "limit output of a record to this many times its size, negative means no limit")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: unpack [options] [file ...]\nWithout files or with \"-\" records are read from stdin.\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	status := 0
+	for _, name := range files {
+		var failed bool
+		var err error
+		if name == "-" {
+			failed, err = processRecords("stdin", stdin, out, stderr, cfg)
+		} else {
+			var f *os.File
+			f, err = os.Open(name)
+			if err == nil {
+				failed, err = processRecords(name, f, out, stderr, cfg)
+				f.Close()
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			status = 2
+		} else if failed && status == 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+// processRecords writes the result of every record to out, errors of single records
+// are reported to stderr without stopping the batch. A failed record produces an empty
+// output record, so the output stays aligned with the input. A trailing '\r' of CRLF lines is dropped.
+func processRecords(name string, in io.Reader, out *bufio.Writer, stderr io.Writer, cfg config) (bool, error) {
+	sep := byte('\n')
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	if cfg.null {
+		sep = 0
+		scanner.Split(scanNull)
+	}
+
+	failed := false
+	for num := 1; scanner.Scan(); num++ {
+		record := scanner.Text()
+		if !cfg.null {
+			record = strings.TrimSuffix(record, "\r")
+		}
+		if cfg.pack {
+			out.WriteString(task9.PackStringWithOptions(record, cfg.opts))
+			out.WriteByte(sep)
+			continue
+		}
 
-	output, err := task9.UnpackString(input)
-	if err != nil {
-		fmt.Printf("error: UnpackString - %v\n", err)
-	} else {
-		fmt.Println(output)
+		output, err := task9.UnpackStringWithOptions(record, cfg.opts)
+		if err != nil {
+			failed = true
+			fmt.Fprintf(stderr, "%s:%d: %v\n", name, num, err)
+			var fmtErr *task9.FormatError
+			if errors.As(err, &fmtErr) {
+				fmt.Fprintln(stderr, fmtErr.Diagnostic(record))
+			}
+			out.WriteByte(sep)
+			continue
+		}
+		out.WriteString(output)
+		out.WriteByte(sep)
+	}
+	if err := scanner.Err(); err != nil {
+		return failed, fmt.Errorf("%s: %w", name, err)
+	}
+	return failed, nil
+}
+
+// scanNull is a bufio.SplitFunc for NUL separated records
+func scanNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
 	}
+	return 0, nil, nil
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		input  string
+		output string
+		status int
+	}{
+		{"unpack lines", nil, "a4bc2\nqwe\\45\n", "aaaabcc\nqwe44444\n", 0},
+		{"errors do not stop batch", nil, "a2\n45\nb3\n", "aa\n\nbbb\n", 1},
+		{"crlf", nil, "a2\r\nb3\r\n", "aa\nbbb\n", 0},
+		{"pack crlf", []string{"-pack"}, "aab\r\n", "a2b\n", 0},
+		{"null keeps cr", []string{"--null"}, "a\r2\x00", "a\r\r\x00", 0},
+		{"default expansion limit", []string{"-multi-digit"}, "a9999\nb2\n", "\nbb\n", 1},
+		{"expansion limit disabled", []string{"-multi-digit", "-max-expansion", "-1"}, "a1000\n", strings.Repeat("a", 1000) + "\n", 0},
+		{"pack", []string{"-pack"}, "aaaab\n", "a4b\n", 0},
+		{"null records", []string{"--null"}, "a2\nb\x00c3\x00", "aa\nb\x00ccc\x00", 0},
+		{"multi digit", []string{"-multi-digit"}, "a12\n", "aaaaaaaaaaaa\n", 0},
+		{"unknown flag", []string{"-x"}, "", "", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.args, strings.NewReader(test.input), &stdout, &stderr)
+			if status != test.status {
+				t.Errorf("wrong status\nexpected: %d\nactual: %d\nstderr: %s", test.status, status, stderr.String())
+			}
+			if stdout.String() != test.output {
+				t.Errorf("wrong output\nexpected: %q\nactual: %q", test.output, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(good, []byte("a3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bad, []byte("x\nab12\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{good, bad, filepath.Join(dir, "missing.txt")}, strings.NewReader(""), &stdout, &stderr)
+	if status != 2 {
+		t.Errorf("expected status 2, got %d", status)
+	}
+	if stdout.String() != "aaa\nx\n\n" {
+		t.Errorf("wrong output: %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "bad.txt:2: wrong string format") || !strings.Contains(stderr.String(), "ab12\n   ^") {
+		t.Errorf("wrong diagnostics:\n%s", stderr.String())
+	}
+}
@@ -0,0 +1,77 @@
+package task9
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reason is the kind of a format error
+type Reason int
+
+const (
+	// ReasonLeadingDigit - a count is not preceded by a letter
+	ReasonLeadingDigit Reason = iota + 1
+	// ReasonDanglingEscape - the input ends with '\'
+	ReasonDanglingEscape
+	// ReasonCountOverflow - a multi-digit count is too large
+	ReasonCountOverflow
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonLeadingDigit:
+		return "digit without preceding letter"
+	case ReasonDanglingEscape:
+		return "escape at the end of input"
+	case ReasonCountOverflow:
+		return "count overflow"
+	}
+	return fmt.Sprintf("Reason(%d)", int(r))
+}
+
+// FormatError describes a malformed packed string, match it with errors.As
+type FormatError struct {
+	Offset     int // смещение в рунах от начала входа
+	ByteOffset int // смещение в байтах от начала входа
+	Rune       rune
+	Reason     Reason
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("wrong string format: %s %q at rune %d", e.Reason, e.Rune, e.Offset)
+}
+
+// Diagnostic returns the line of input containing the error with a caret under the bad rune:
+//
+//	ab12
+//	   ^
+func (e *FormatError) Diagnostic(input string) string {
+	runes := []rune(input)
+	if e.Offset > len(runes) {
+		return input
+	}
+
+	// Показываем только строку с ошибкой, если вход многострочный
+	lineStart := e.Offset
+	for lineStart > 0 && runes[lineStart-1] != '\n' {
+		lineStart--
+	}
+	lineEnd := e.Offset
+	for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+		lineEnd++
+	}
+
+	bldr := strings.Builder{}
+	bldr.WriteString(string(runes[lineStart:lineEnd]))
+	bldr.WriteByte('\n')
+	for _, r := range runes[lineStart:e.Offset] {
+		// Табуляция сохраняется, чтобы каретка совпала с позицией в терминале
+		if r == '\t' {
+			bldr.WriteByte('\t')
+		} else {
+			bldr.WriteByte(' ')
+		}
+	}
+	bldr.WriteByte('^')
+	return bldr.String()
+}
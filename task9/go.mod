@@ -2,6 +2,8 @@ module github.com/pozedorum/WB_project_2.8/task9
 
 go 1.24.1
 
+require github.com/rivo/uniseg v0.4.7
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
@@ -0,0 +1,119 @@
+package task9
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"testing/quick"
+)
+
+func TestUnpackGraphemes(t *testing.T) {
+	const (
+		eAcute = "e\u0301"                                    // e + комбинируемое ударение
+		flag   = "\U0001F1F7\U0001F1FA"                       // флаг из двух региональных индикаторов
+		thumb  = "\U0001F44D\U0001F3FD"                       // эмодзи с модификатором цвета кожи
+		family = "\U0001F468\u200d\U0001F469\u200d\U0001F467" // ZWJ последовательность
+		hangul = "\u1100\u1161\u11a8"                         // слог из чамо
+	)
+
+	tests := []struct {
+		name   string
+		input  string
+		output string
+	}{
+		{"combining mark", eAcute + "3", strings.Repeat(eAcute, 3)},
+		{"several marks", "a\u0323\u0301" + "2b", "a\u0323\u0301a\u0323\u0301b"},
+		{"flag", flag + "2", flag + flag},
+		{"two flags", flag + flag + "2", flag + flag + flag},
+		{"skin tone", thumb + "4", strings.Repeat(thumb, 4)},
+		{"zwj sequence", family + "2", family + family},
+		{"hangul jamo", hangul + "2", hangul + hangul},
+		{"escaped digit", "\\4" + eAcute + "2", "4" + eAcute + eAcute},
+		{"crlf", "\r\n3", "\r\n\r\n\r\n"},
+		{"mark after count", "\n2\u0301", "\n\n\u0301"},
+		{"spacing mark after count", "\r3\u0903", "\r\r\r\u0903"},
+		{"escaped digit with mark", "\\2\u0301" + "3", strings.Repeat("2\u0301", 3)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := UnpackStringWithOptions(test.input, Options{Graphemes: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if output != test.output {
+				t.Errorf("wrong output string\nexpected: %q\nactual: %q", test.output, output)
+			}
+
+			// Потоковый режим по одному байту должен находить те же границы кластеров
+			streamed, err := io.ReadAll(NewUnpackerWithOptions(iotest.OneByteReader(strings.NewReader(test.input)), Options{Graphemes: true}))
+			if err != nil {
+				t.Fatalf("unexpected stream error: %v", err)
+			}
+			if string(streamed) != test.output {
+				t.Errorf("wrong stream output\nexpected: %q\nactual: %q", test.output, streamed)
+			}
+
+			packed := PackStringWithOptions(test.output, Options{Graphemes: true})
+			if unpacked, err := UnpackStringWithOptions(packed, Options{Graphemes: true}); err != nil || unpacked != test.output {
+				t.Errorf("round trip failed for %q: %q %v", test.output, unpacked, err)
+			}
+		})
+	}
+
+	// В режиме рун повторяется только последняя руна кластера
+	if output, _ := UnpackString("e\u0301" + "2"); output != "e\u0301\u0301" {
+		t.Errorf("rune mode changed: %q", output)
+	}
+}
+
+func TestPackGraphemes(t *testing.T) {
+	thumb := "\U0001F44D\U0001F3FD"
+	if packed := PackStringWithOptions(strings.Repeat(thumb, 3), Options{Graphemes: true}); packed != thumb+"3" {
+		t.Errorf("expected %q, got %q", thumb+"3", packed)
+	}
+}
+
+func TestGraphemesErrorOffset(t *testing.T) {
+	_, err := UnpackStringWithOptions("e\u0301"+"\U0001F1F7\U0001F1FA"+"2"+"3", Options{Graphemes: true})
+	var fmtErr *FormatError
+	if !errors.As(err, &fmtErr) {
+		t.Fatalf("expected FormatError, got %v", err)
+	}
+	// Смещения считаются в рунах и байтах, а не в кластерах
+	if fmtErr.Offset != 5 || fmtErr.ByteOffset != 12 || fmtErr.Rune != '3' {
+		t.Errorf("wrong error: %+v", *fmtErr)
+	}
+}
+
+func TestPackUnpackGraphemesRoundTrip(t *testing.T) {
+	// Алфавит из символов, влияющих на границы кластеров: цифры, метки, ZWJ, флаги, управляющие
+	alphabet := []string{
+		"a", "e", "1", "2", "\\", "\n", "\r", "\u0301", "\u0903", "\u200d",
+		"\U0001F1F7", "\U0001F44D", "\U0001F3FD", "\u1100", "\u1161", "\u0600",
+	}
+	for _, opts := range []Options{{Graphemes: true}, {Graphemes: true, MultiDigit: true, MaxExpansion: -1}} {
+		roundTrip := func(indices []uint8, counts []uint8) bool {
+			bldr := strings.Builder{}
+			for i, ind := range indices {
+				count := 1
+				if i < len(counts) {
+					count = int(counts[i]%12) + 1
+				}
+				bldr.WriteString(strings.Repeat(alphabet[int(ind)%len(alphabet)], count))
+			}
+			s := bldr.String()
+			output, err := UnpackStringWithOptions(PackStringWithOptions(s, opts), opts)
+			if err != nil || output != s {
+				t.Logf("%q: %q %v", s, output, err)
+				return false
+			}
+			return true
+		}
+		if err := quick.Check(roundTrip, &quick.Config{MaxCount: 3000}); err != nil {
+			t.Errorf("options %+v: %v", opts, err)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package task9
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"testing/quick"
 )
 
 type testStruct struct {
@@ -19,6 +22,10 @@ func TestUnpackString(t *testing.T) {
 		{"", "", false},
 		{"qwe\\4\\5", "qwe45", false},
 		{"qwe\\45", "qwe44444", false},
+		// Только ASCII цифры являются счётчиками, остальные цифры Unicode - обычные буквы
+		{"a٣", "a٣", false},
+		{"٣2", "٣٣", false},
+		{"a３b", "a３b", false},
 	}
 
 	for ind, test := range testArr {
@@ -35,3 +42,113 @@ func TestUnpackString(t *testing.T) {
 		})
 	}
 }
+
+func TestUnpackStringMultiDigit(t *testing.T) {
+	testArr := []testStruct{
+		{"a12", "aaaaaaaaaaaa", false},
+		{"a12b", "aaaaaaaaaaaab", false},
+		{"a10b1", "aaaaaaaaaab", false},
+		{"a1\\2", "a2", false},
+		{"a99999999999", "", true},
+		{"12", "", true},
+	}
+
+	for ind, test := range testArr {
+		t.Run(fmt.Sprintf("test %d", ind), func(t *testing.T) {
+			output, err := UnpackStringWithOptions(test.input, Options{MultiDigit: true})
+
+			if (err != nil) != test.err {
+				t.Errorf("wrong error\nexpected: %v\nactual: %v", test.err, err)
+			}
+
+			if output != test.output {
+				t.Errorf("wrong output string\nexpected: %s\nactual: %s", test.output, output)
+			}
+		})
+	}
+
+	// В режиме одной цифры вторая цифра подряд является ошибкой
+	if _, err := UnpackString("a12"); err == nil {
+		t.Errorf("expected error for a12 without MultiDigit")
+	}
+}
+
+func TestPackString(t *testing.T) {
+	testArr := []struct {
+		input      string
+		output     string
+		multiDigit bool
+	}{
+		{"", "", false},
+		{"abcd", "abcd", false},
+		{"aaaabccddddde", "a4bc2d5e", false},
+		{"qwe45", "qwe\\4\\5", false},
+		{"qwe44444", "qwe\\45", false},
+		{"a\\\\b", "a\\\\2b", false},
+		{"aaaaaaaaaaaa", "a9a3", false},
+		{"aaaaaaaaaaaa", "a12", true},
+		{"ёёё", "ё3", false},
+	}
+
+	for ind, test := range testArr {
+		t.Run(fmt.Sprintf("test %d", ind), func(t *testing.T) {
+			output := PackStringWithOptions(test.input, Options{MultiDigit: test.multiDigit})
+			if output != test.output {
+				t.Errorf("wrong output string\nexpected: %s\nactual: %s", test.output, output)
+			}
+		})
+	}
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	// Длинная серия "a12000" законно превышает DefaultMaxExpansion, поэтому лимит отключён
+	for _, opts := range []Options{{}, {MultiDigit: true, MaxExpansion: -1}} {
+		roundTrip := func(s string) bool {
+			output, err := UnpackStringWithOptions(PackStringWithOptions(s, opts), opts)
+			return err == nil && output == s
+		}
+		if err := quick.Check(roundTrip, &quick.Config{MaxCount: 2000}); err != nil {
+			t.Errorf("options %+v: %v", opts, err)
+		}
+
+		// Случайные строки почти не содержат повторов, поэтому отдельно проверяем длинные серии
+		runs := func(letters []rune, counts []uint8) bool {
+			bldr := strings.Builder{}
+			for i, r := range letters {
+				if i < len(counts) {
+					bldr.WriteString(strings.Repeat(string(r), int(counts[i])))
+				}
+			}
+			return roundTrip(bldr.String())
+		}
+		if err := quick.Check(runs, &quick.Config{MaxCount: 500}); err != nil {
+			t.Errorf("options %+v: %v", opts, err)
+		}
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	_, err := UnpackString("qw\te45")
+	var fmtErr *FormatError
+	if !errors.As(err, &fmtErr) {
+		t.Fatalf("expected FormatError, got %v", err)
+	}
+	if fmtErr.Reason != ReasonLeadingDigit || fmtErr.Offset != 5 || fmtErr.Rune != '5' {
+		t.Errorf("wrong error: %+v", *fmtErr)
+	}
+
+	expected := "qw\te45\n  \t  ^"
+	if diag := fmtErr.Diagnostic("qw\te45"); diag != expected {
+		t.Errorf("wrong diagnostic\nexpected:\n%s\nactual:\n%s", expected, diag)
+	}
+
+	// Для многострочного входа выводится только строка с ошибкой
+	input := "abc\nd\\"
+	_, err = UnpackString(input)
+	if !errors.As(err, &fmtErr) || fmtErr.Reason != ReasonDanglingEscape {
+		t.Fatalf("expected dangling escape, got %v", err)
+	}
+	if diag := fmtErr.Diagnostic(input); diag != "d\\\n ^" {
+		t.Errorf("wrong diagnostic:\n%s", diag)
+	}
+}
@@ -0,0 +1,269 @@
+package task9
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// DefaultMaxExpansion is the expansion limit used by NewUnpacker and UnpackString
+	DefaultMaxExpansion = 1000
+	// chunkSize limits the amount of output produced at once by a long repeat
+	chunkSize = 32 * 1024
+)
+
+var (
+	// ErrExpansionLimit is returned when the output grows beyond Options.MaxExpansion times the input
+	ErrExpansionLimit = errors.New("expansion limit exceeded")
+)
+
+// OffsetError describes a failure other than *FormatError at a position of the packed input
+type OffsetError struct {
+	ByteOffset int64 // смещение в байтах от начала входа
+	RuneOffset int64 // смещение в рунах от начала входа
+	Err        error
+}
+
+func (e *OffsetError) Error() string {
+	return fmt.Sprintf("%v at rune %d (byte %d)", e.Err, e.RuneOffset, e.ByteOffset)
+}
+
+func (e *OffsetError) Unwrap() error { return e.Err }
+
+// unit is the repeatable element of the input: a rune or a grapheme cluster
+type unit struct {
+	s                string
+	byteOff, runeOff int64
+}
+
+// isDigit reports whether the unit starts with a count digit. In the graphemes mode
+// a digit forms one cluster with the following combining or spacing marks ("2\u0301").
+func (un unit) isDigit() bool  { return len(un.s) > 0 && isCountDigit(rune(un.s[0])) }
+func (un unit) isEscape() bool { return un.s == "\\" }
+
+// marks returns the part of a digit cluster after the digit, it belongs to the next letter
+func (un unit) marks() (unit, bool) {
+	return unit{un.s[1:], un.byteOff + 1, un.runeOff + 1}, len(un.s) > 1
+}
+
+// Unpacker decodes a packed stream, it is an io.Reader of the unpacked data
+// and can be used with io.Copy. Memory use does not depend on the input size.
+type Unpacker struct {
+	r    *bufio.Reader
+	opts Options
+
+	out  []byte // готовые к выдаче байты
+	pend []byte // буфер для повторов, переиспользуется
+
+	letter    string
+	hasLetter bool // отдельный флаг, чтобы руна '\x00' тоже могла повторяться
+	repeat    int  // сколько раз ещё нужно записать letter
+	back      *unit
+
+	// Для режима графем: руны, для которых ещё не найдена граница кластера
+	gbuf   []byte
+	gsizes []int // исходные размеры рун в байтах, для невалидного UTF-8 отличаются от gbuf
+	gstate int
+	eof    bool
+
+	inBytes, inRunes   int64 // прочитано из источника
+	posBytes, posRunes int64 // начало следующей единицы
+	outBytes           int64
+	err                error
+}
+
+// NewUnpacker returns a streaming decoder with single digit counts and DefaultMaxExpansion
+func NewUnpacker(r io.Reader) *Unpacker {
+	return NewUnpackerWithOptions(r, Options{MaxExpansion: DefaultMaxExpansion})
+}
+
+// NewUnpackerWithOptions returns a streaming decoder with the given rules
+func NewUnpackerWithOptions(r io.Reader, opts Options) *Unpacker {
+	return &Unpacker{r: bufio.NewReader(r), opts: opts, gstate: -1}
+}
+
+// Read implements io.Reader
+func (u *Unpacker) Read(p []byte) (int, error) {
+	for len(u.out) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		u.err = u.fill()
+	}
+	n := copy(p, u.out)
+	u.out = u.out[n:]
+	return n, nil
+}
+
+// fill decodes input until some output is ready or an error occurs
+func (u *Unpacker) fill() error {
+	u.pend = u.pend[:0]
+	if u.repeat > 0 {
+		u.flushRepeat()
+		return nil
+	}
+
+	for len(u.pend) == 0 {
+		un, err := u.next()
+		if err == io.EOF {
+			u.emitLetter()
+			u.out = u.pend
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case un.isEscape():
+			escaped, err := u.next()
+			if err == io.EOF {
+				return formatError(un, ReasonDanglingEscape)
+			}
+			if err != nil {
+				return err
+			}
+			u.emitLetter()
+			u.letter, u.hasLetter = escaped.s, true
+		case un.isDigit():
+			if !u.hasLetter {
+				return formatError(un, ReasonLeadingDigit)
+			}
+			count, err := u.readCount(un)
+			if err != nil {
+				return err
+			}
+			u.repeat, u.hasLetter = count, false
+			u.flushRepeat()
+		default:
+			u.emitLetter()
+			u.letter, u.hasLetter = un.s, true
+		}
+	}
+	u.out = u.pend
+	return nil
+}
+
+// next returns the next rune or grapheme cluster of the input
+func (u *Unpacker) next() (unit, error) {
+	if u.back != nil {
+		un := *u.back
+		u.back = nil
+		return un, nil
+	}
+	if u.opts.Graphemes {
+		return u.nextCluster()
+	}
+
+	r, size, err := u.r.ReadRune()
+	if err != nil {
+		return unit{}, err
+	}
+	un := unit{string(r), u.inBytes, u.inRunes}
+	u.inBytes += int64(size)
+	u.inRunes++
+	return un, nil
+}
+
+// nextCluster reads runes until the end of the current grapheme cluster is known
+func (u *Unpacker) nextCluster() (unit, error) {
+	for {
+		if len(u.gbuf) > 0 {
+			cluster, rest, _, state := uniseg.FirstGraphemeCluster(u.gbuf, u.gstate)
+			// Граница кластера известна, только если за ним уже прочитана руна
+			if len(rest) > 0 || u.eof {
+				n := utf8.RuneCount(cluster)
+				un := unit{string(cluster), u.posBytes, u.posRunes}
+				for _, size := range u.gsizes[:n] {
+					u.posBytes += int64(size)
+				}
+				u.posRunes += int64(n)
+				u.gsizes = u.gsizes[:copy(u.gsizes, u.gsizes[n:])]
+				u.gbuf = u.gbuf[:copy(u.gbuf, rest)]
+				u.gstate = state
+				return un, nil
+			}
+		}
+		if u.eof {
+			return unit{}, io.EOF
+		}
+
+		r, size, err := u.r.ReadRune()
+		if err == io.EOF {
+			u.eof = true
+			continue
+		}
+		if err != nil {
+			return unit{}, err
+		}
+		u.inBytes += int64(size)
+		u.inRunes++
+		u.gbuf = utf8.AppendRune(u.gbuf, r)
+		u.gsizes = append(u.gsizes, size)
+	}
+}
+
+// readCount reads the rest of a count started by first and checks the expansion limit
+func (u *Unpacker) readCount(first unit) (int, error) {
+	count := int(first.s[0] - '0')
+	if rest, ok := first.marks(); ok {
+		u.back = &rest
+	}
+	for u.opts.MultiDigit && u.back == nil {
+		un, err := u.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !un.isDigit() {
+			u.back = &un
+			break
+		}
+		count = count*10 + int(un.s[0]-'0')
+		if count > math.MaxInt32 {
+			return 0, formatError(first, ReasonCountOverflow)
+		}
+		if rest, ok := un.marks(); ok {
+			u.back = &rest
+		}
+	}
+
+	if u.opts.MaxExpansion > 0 {
+		total := u.outBytes + int64(count*len(u.letter))
+		if float64(total) > u.opts.MaxExpansion*float64(u.inBytes) {
+			return 0, &OffsetError{ByteOffset: first.byteOff, RuneOffset: first.runeOff, Err: ErrExpansionLimit}
+		}
+	}
+	return count, nil
+}
+
+func (u *Unpacker) emitLetter() {
+	if u.hasLetter {
+		u.pend = append(u.pend, u.letter...)
+		u.outBytes += int64(len(u.letter))
+		u.hasLetter = false
+	}
+}
+
+// flushRepeat writes up to chunkSize bytes of the pending repeat, at least one letter
+func (u *Unpacker) flushRepeat() {
+	for u.repeat > 0 && (len(u.pend) == 0 || len(u.pend)+len(u.letter) <= chunkSize) {
+		u.pend = append(u.pend, u.letter...)
+		u.outBytes += int64(len(u.letter))
+		u.repeat--
+	}
+	u.out = u.pend
+}
+
+func formatError(un unit, reason Reason) error {
+	r, _ := utf8.DecodeRuneInString(un.s)
+	return &FormatError{Offset: int(un.runeOff), ByteOffset: int(un.byteOff), Rune: r, Reason: reason}
+}
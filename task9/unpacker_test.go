@@ -0,0 +1,114 @@
+package task9
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestUnpackerMatchesUnpackString(t *testing.T) {
+	inputs := []string{"abcd", "a4bc2d5e", "", "qwe\\4\\5", "qwe\\45", "ё3ж\\\\2", "a0b"}
+
+	for ind, input := range inputs {
+		t.Run(fmt.Sprintf("test %d", ind), func(t *testing.T) {
+			expected, err := UnpackString(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// По одному байту, чтобы экранирование и многобайтовые руны попадали на границы буфера
+			var bldr strings.Builder
+			_, err = io.Copy(&bldr, NewUnpacker(iotest.OneByteReader(strings.NewReader(input))))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bldr.String() != expected {
+				t.Errorf("wrong output string\nexpected: %s\nactual: %s", expected, bldr.String())
+			}
+		})
+	}
+}
+
+func TestUnpackerMultiDigitAcrossBoundary(t *testing.T) {
+	u := NewUnpackerWithOptions(iotest.HalfReader(strings.NewReader("x\\1123y")), Options{MultiDigit: true})
+	output, err := io.ReadAll(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "x" + strings.Repeat("1", 123) + "y"; string(output) != expected {
+		t.Errorf("wrong output string\nexpected: %s\nactual: %s", expected, output)
+	}
+}
+
+func TestUnpackerLargeRepeat(t *testing.T) {
+	u := NewUnpackerWithOptions(strings.NewReader("ж9999999"), Options{MultiDigit: true})
+	n, err := io.Copy(io.Discard, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2*9999999 {
+		t.Errorf("expected %d bytes, got %d", 2*9999999, n)
+	}
+}
+
+func TestUnpackerErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		opts       Options
+		byteOffset int
+		runeOffset int
+		r          rune
+		reason     Reason
+	}{
+		{"45", Options{}, 0, 0, '4', ReasonLeadingDigit},
+		{"абв\\", Options{}, 6, 3, '\\', ReasonDanglingEscape},
+		{"ab12", Options{}, 3, 3, '2', ReasonLeadingDigit},
+		{"a99999999999", Options{MultiDigit: true}, 1, 1, '9', ReasonCountOverflow},
+	}
+
+	for ind, test := range tests {
+		t.Run(fmt.Sprintf("test %d", ind), func(t *testing.T) {
+			_, err := io.Copy(io.Discard, NewUnpackerWithOptions(strings.NewReader(test.input), test.opts))
+			var fmtErr *FormatError
+			if !errors.As(err, &fmtErr) {
+				t.Fatalf("expected FormatError, got %v", err)
+			}
+			expected := FormatError{test.runeOffset, test.byteOffset, test.r, test.reason}
+			if *fmtErr != expected {
+				t.Errorf("wrong error\nexpected: %+v\nactual: %+v", expected, *fmtErr)
+			}
+		})
+	}
+
+	_, err := io.Copy(io.Discard, NewUnpackerWithOptions(strings.NewReader("ыa9"), Options{MaxExpansion: 2}))
+	var offErr *OffsetError
+	if !errors.As(err, &offErr) || !errors.Is(err, ErrExpansionLimit) {
+		t.Fatalf("expected OffsetError with ErrExpansionLimit, got %v", err)
+	}
+	if offErr.ByteOffset != 3 || offErr.RuneOffset != 2 {
+		t.Errorf("wrong offset: byte %d rune %d", offErr.ByteOffset, offErr.RuneOffset)
+	}
+}
+
+func TestUnpackerDefaultLimit(t *testing.T) {
+	// Бомба: короткий вход разворачивается в гигабайты
+	u := NewUnpackerWithOptions(strings.NewReader("a999999999"), Options{MultiDigit: true, MaxExpansion: DefaultMaxExpansion})
+	if _, err := io.Copy(io.Discard, u); !errors.Is(err, ErrExpansionLimit) {
+		t.Errorf("expected ErrExpansionLimit, got %v", err)
+	}
+	if _, err := io.Copy(io.Discard, NewUnpacker(strings.NewReader("a9b9c9"))); err != nil {
+		t.Errorf("unexpected error for normal input: %v", err)
+	}
+
+	// Без явного лимита UnpackStringWithOptions защищена так же, как NewUnpacker
+	if _, err := UnpackStringWithOptions("a999999999", Options{MultiDigit: true}); !errors.Is(err, ErrExpansionLimit) {
+		t.Errorf("expected ErrExpansionLimit from UnpackStringWithOptions, got %v", err)
+	}
+	// Отрицательный лимит отключает проверку
+	output, err := UnpackStringWithOptions("a5000", Options{MultiDigit: true, MaxExpansion: -1})
+	if err != nil || len(output) != 5000 {
+		t.Errorf("expected 5000 letters without limit, got %d, %v", len(output), err)
+	}
+}
@@ -2,51 +2,106 @@ package task9
 
 import (
 	"fmt"
+	"io"
 	"strings"
-	"unicode"
+
+	"github.com/rivo/uniseg"
 )
 
-// UnpackString take string and for each pair "letter, digit" make sequence of digit letters
+// Options configures packing and unpacking rules
+type Options struct {
+	// MultiDigit allows counts of several digits ("a12" -> twelve letters),
+	// otherwise only one digit after a letter is consumed
+	MultiDigit bool
+	// MaxExpansion limits the output size to MaxExpansion times the input size
+	// to protect from decompression bombs. Zero means no limit for the streaming Unpacker and
+	// DefaultMaxExpansion for UnpackStringWithOptions, which keeps the whole output in memory;
+	// a negative value disables the limit everywhere
+	MaxExpansion float64
+	// Graphemes makes extended grapheme clusters (UAX #29) the repeatable unit instead of runes,
+	// so "é3" repeats e with the combining accent and "👍🏽2" keeps the skin tone
+	Graphemes bool
+}
+
+// UnpackString take string and for each pair "letter, digit" make sequence of digit letters.
+// Only ASCII digits 0-9 are counts, other Unicode digits ('٣', '３') are ordinary letters.
 func UnpackString(input string) (string, error) {
-	inputRune := []rune(input)
-	inputLen := len(inputRune)
+	return UnpackStringWithOptions(input, Options{})
+}
+
+// UnpackStringWithOptions works as UnpackString with configurable rules
+func UnpackStringWithOptions(input string, opts Options) (string, error) {
+	if opts.MaxExpansion == 0 {
+		opts.MaxExpansion = DefaultMaxExpansion
+	}
 	bldr := strings.Builder{}
+	if _, err := io.Copy(&bldr, NewUnpackerWithOptions(strings.NewReader(input), opts)); err != nil {
+		return "", err
+	}
+	return bldr.String(), nil
+}
 
-	var letter rune = 0
-	for ind := 0; ind < inputLen; ind++ {
-		if inputRune[ind] == '\\' {
-			if ind == inputLen-1 {
-				return "", fmt.Errorf("wrong string format")
-			}
-			if letter != 0 {
-				bldr.WriteRune(letter)
+// PackString is the inverse of UnpackString: runs of equal letters are replaced by the letter
+// and the run length, digits and backslashes are escaped with '\'.
+// UnpackString(PackString(s)) == s for any s.
+func PackString(input string) string {
+	return PackStringWithOptions(input, Options{})
+}
+
+// PackStringWithOptions works as PackString, with single digit counts long runs are split ("a12" -> "a9a3")
+func PackStringWithOptions(input string, opts Options) string {
+	units := splitUnits(input, opts.Graphemes)
+	bldr := strings.Builder{}
+
+	for ind := 0; ind < len(units); {
+		letter := units[ind]
+		run := 1
+		for ind+run < len(units) && units[ind+run] == letter {
+			run++
+		}
+		ind += run
+
+		for run > 0 {
+			count := run
+			if !opts.MultiDigit {
+				count = min(run, 9)
 			}
-			ind++
-			letter = inputRune[ind]
-		} else if !unicode.IsDigit(inputRune[ind]) {
-			if letter != 0 {
-				_, err := bldr.WriteRune(letter)
-				if err != nil {
-					return "", err
-				}
+			run -= count
+
+			// Кластер, начинающийся с цифры ("2\u0301"), тоже экранируется, иначе он станет счётчиком
+			if letter == "\\" || isCountDigit(rune(letter[0])) {
+				bldr.WriteRune('\\')
 			}
-			letter = inputRune[ind]
-		} else if unicode.IsDigit(inputRune[ind]) {
-			if letter == 0 {
-				return "", fmt.Errorf("wrong string format")
+			bldr.WriteString(letter)
+			// Одиночная буква записывается без счётчика
+			if count > 1 {
+				fmt.Fprint(&bldr, count)
 			}
+		}
+	}
+	return bldr.String()
+}
 
-			for range int(inputRune[ind] - '0') {
-				bldr.WriteRune(letter)
-			}
-			letter = 0
-		} else {
-			bldr.WriteRune(inputRune[ind])
-			letter = 0
+// splitUnits splits input into runes or grapheme clusters
+func splitUnits(input string, graphemes bool) []string {
+	units := make([]string, 0, len(input))
+	if graphemes {
+		state := -1
+		for len(input) > 0 {
+			var cluster string
+			cluster, input, _, state = uniseg.FirstGraphemeClusterInString(input, state)
+			units = append(units, cluster)
 		}
+		return units
 	}
-	if letter != 0 {
-		bldr.WriteRune(letter)
+	for _, r := range input {
+		units = append(units, string(r))
 	}
-	return bldr.String(), nil
+	return units
+}
+
+// isCountDigit reports whether r is a count digit. Only ASCII digits are counts:
+// the value of other Unicode digits is not r - '0', and PackString must escape every count digit.
+func isCountDigit(r rune) bool {
+	return r >= '0' && r <= '9'
 }